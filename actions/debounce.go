@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// debouncer collapses Docker events, that trigger the same test container
+// during debounce window, into a single run.
+type debouncer struct {
+	mu      sync.Mutex
+	pending map[string]*debounced
+}
+
+type debounced struct {
+	c      container.Container
+	events []*dockerclient.Event
+	timer  *time.Timer
+}
+
+func newDebouncer() *debouncer {
+	return &debouncer{pending: make(map[string]*debounced)}
+}
+
+// add adds event e to test container c pending events. The first event opens
+// debounce window, fire is called with all collected events when window is closed.
+// add returns true if e opened a new window.
+func (d *debouncer) add(c container.Container, e *dockerclient.Event, window time.Duration,
+	fire func(container.Container, []*dockerclient.Event)) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p, ok := d.pending[c.ID()]; ok {
+		p.c = c
+		p.events = append(p.events, e)
+		return false
+	}
+	p := &debounced{c: c, events: []*dockerclient.Event{e}}
+	d.pending[c.ID()] = p
+	p.timer = time.AfterFunc(window, func() {
+		d.mu.Lock()
+		p := d.pending[c.ID()]
+		delete(d.pending, c.ID())
+		d.mu.Unlock()
+		fire(p.c, p.events)
+	})
+
+	return true
+}
+
+// stop cancels all pending debounce windows, their events are dropped.
+// stop returns test containers of cancelled windows.
+func (d *debouncer) stop() []container.Container {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ret []container.Container
+	for id, p := range d.pending {
+		// window, that is already closing, fires anyway
+		if p.timer.Stop() {
+			delete(d.pending, id)
+			ret = append(ret, p.c)
+		}
+	}
+
+	return ret
+}
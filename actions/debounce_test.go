@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDebouncerAdd(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	c := newTestCandidate("candidate", nil)
+	e1 := &dockerclient.Event{Action: "create"}
+	e2 := &dockerclient.Event{Action: "start"}
+	var fired []*dockerclient.Event
+	fire := func(fc container.Container, events []*dockerclient.Event) {
+		assert.Equal(t, c.ID(), fc.ID())
+		fired = events
+		wg.Done()
+	}
+
+	d := newDebouncer()
+	assert.True(t, d.add(c, e1, time.Millisecond*20, fire))
+	assert.False(t, d.add(c, e2, time.Millisecond*20, fire))
+	wg.Wait()
+
+	assert.Equal(t, []*dockerclient.Event{e1, e2}, fired)
+	assert.Empty(t, d.pending)
+}
+
+func TestDebouncerStop(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	fire := func(container.Container, []*dockerclient.Event) {
+		assert.Fail(t, "cancelled window fired")
+	}
+
+	d := newDebouncer()
+	assert.True(t, d.add(c, &dockerclient.Event{Action: "start"}, time.Millisecond*10, fire))
+	cancelled := d.stop()
+	time.Sleep(time.Millisecond * 30)
+
+	assert.Len(t, cancelled, 1)
+	assert.Equal(t, c.ID(), cancelled[0].ID())
+	assert.Empty(t, d.pending)
+}
+
+func TestRunnerStop_Debounce(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotEventDockerDebounce: "10ms"})
+	client := mockclient.NewMockClient()
+
+	runner := NewRunner(client, RunnerConfig{})
+	assert.NoError(t, runner.StartContainerFromEvent(c, &dockerclient.Event{Action: "start"}))
+	runner.Stop()
+	runner.Wait()
+	time.Sleep(time.Millisecond * 30)
+
+	client.AssertNotCalled(t, "StartContainerFrom", mock.Anything, mock.Anything)
+}
+
+func TestRunnerStartContainerFromEvent_Debounce(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotEventDockerDebounce: "10ms"})
+	events := []*dockerclient.Event{{Action: "create"}, {Action: "start"}, {Action: "start"}}
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			run := args.Get(1).(*container.RunResult)
+			assert.Equal(t, events, run.Events)
+			assert.Equal(t, events[2], run.Event)
+		}).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&exited, nil).Once()
+
+	runner := NewRunner(client, RunnerConfig{})
+	for _, e := range events {
+		assert.NoError(t, runner.StartContainerFromEvent(c, e))
+	}
+	runner.Wait()
+
+	client.AssertExpectations(t)
+}
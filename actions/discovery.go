@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"sync"
+
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// discovery keeps track of test container candidates seen by the ticker, in order
+// to run test containers once on tugbot startup or when a new candidate is discovered.
+type discovery struct {
+	mu      sync.Mutex
+	known   map[string]bool
+	startup bool
+}
+
+func newDiscovery() *discovery {
+	return &discovery{known: make(map[string]bool), startup: true}
+}
+
+// discover marks candidates as known and returns a trigger per candidate, that
+// should run once: on first call 'startup' candidates, afterwards 'discovered' candidates
+// seen for the first time. Candidates missing from a listing stay known (the listing
+// skips containers that failed to inspect), they are forgotten only when destroyed.
+func (d *discovery) discover(candidates []container.Container) map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	triggers := make(map[string]string)
+	for _, c := range candidates {
+		if d.known[c.ID()] {
+			continue
+		}
+		d.known[c.ID()] = true
+		if d.startup {
+			if c.IsStartupListener() {
+				triggers[c.ID()] = container.TriggerStartup
+			}
+		} else if c.IsDiscoveryListener() {
+			triggers[c.ID()] = container.TriggerDiscovered
+		}
+	}
+	d.startup = false
+
+	return triggers
+}
+
+// forget removes a destroyed candidate, a new candidate with the same ID is discovered again.
+func (d *discovery) forget(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.known, id)
+}
@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscovery(t *testing.T) {
+	startup := newTestCandidate("startup", map[string]string{container.TugbotEventStartup: ""})
+	discovered := newTestCandidate("discovered", map[string]string{container.TugbotEventDiscovered: ""})
+	both := newTestCandidate("both", map[string]string{container.TugbotEventStartup: "", container.TugbotEventDiscovered: ""})
+	none := newTestCandidate("none", nil)
+	d := newDiscovery()
+
+	// startup: existing candidates are not discovered
+	assert.Equal(t,
+		map[string]string{"startup": container.TriggerStartup, "both": container.TriggerStartup},
+		d.discover([]container.Container{startup, discovered, both, none}))
+	// no new candidates
+	assert.Empty(t, d.discover([]container.Container{startup, discovered, both, none}))
+	// new candidates
+	newDiscovered := newTestCandidate("new-discovered", map[string]string{container.TugbotEventDiscovered: ""})
+	newStartup := newTestCandidate("new-startup", map[string]string{container.TugbotEventStartup: ""})
+	assert.Equal(t,
+		map[string]string{"new-discovered": container.TriggerDiscovered},
+		d.discover([]container.Container{startup, newDiscovered, newStartup}))
+	// candidates missing from a listing are not discovered again
+	assert.Empty(t, d.discover([]container.Container{newDiscovered}))
+	assert.Empty(t, d.discover([]container.Container{startup, discovered, newDiscovered}))
+	// destroyed candidates are forgotten
+	d.forget(discovered.ID())
+	assert.Equal(t,
+		map[string]string{"discovered": container.TriggerDiscovered},
+		d.discover([]container.Container{discovered}))
+}
@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// Explanation tells whether or not an event triggers a test container run and, if not, why.
+type Explanation struct {
+	Container container.Container
+	Match     bool
+	// Reason is why the event does not trigger a test container run, empty if it does
+	Reason string
+}
+
+// Explain looks at test container candidates, all or by names, the same way Run does and
+// explains whether or not event e triggers their run, without starting them.
+func Explain(client container.Client, names []string, e *dockerclient.Event) ([]Explanation, error) {
+	candidates, err := client.ListContainers(containerFilter(names))
+	if err != nil {
+		return nil, err
+	}
+	ignored := ""
+	if container.IsSwarmTask(e) {
+		ignored = "events of swarm tasks are ignored"
+	} else if container.IsCreatedByTugbot(e) {
+		ignored = "events of containers created by tugbot are ignored"
+	}
+	ret := make([]Explanation, 0, len(candidates))
+	for _, candidate := range candidates {
+		explanation := Explanation{Container: candidate, Reason: ignored}
+		if ignored == "" {
+			explanation.Match, explanation.Reason = candidate.ExplainEvent(e)
+		}
+		ret = append(ret, explanation)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newExplainCandidates() []container.Container {
+	var ret []container.Container
+	for name, labels := range map[string]map[string]string{
+		"a-start": {container.TugbotEventDocker: "", container.ActionFilter: "start"},
+		"b-nginx": {container.TugbotEventDocker: "", container.ImageFilter: "re2:^nginx"},
+		"c-timer": {container.TugbotEventTimer: "1m"},
+	} {
+		ret = append(ret, newTestCandidate(name, labels))
+	}
+
+	return ret
+}
+
+func explanationsByName(explanations []Explanation) map[string]Explanation {
+	ret := make(map[string]Explanation)
+	for _, explanation := range explanations {
+		ret[explanation.Container.Name()] = explanation
+	}
+
+	return ret
+}
+
+func TestExplain(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return(newExplainCandidates(), nil).Once()
+
+	explanations, err := Explain(client, nil, &dockerclient.Event{Type: "container", Action: "start", From: "redis:3"})
+
+	assert.NoError(t, err)
+	byName := explanationsByName(explanations)
+	assert.Equal(t, Explanation{Container: byName["a-start"].Container, Match: true}, byName["a-start"])
+	assert.False(t, byName["b-nginx"].Match)
+	assert.Equal(t, "rejected by tugbot-event-docker-filter-image filter: re2:^nginx", byName["b-nginx"].Reason)
+	assert.False(t, byName["c-timer"].Match)
+	assert.Equal(t, "not subscribed to Docker events, tugbot-event-docker label is missing", byName["c-timer"].Reason)
+	client.AssertExpectations(t)
+}
+
+func TestExplain_CreatedByTugbot(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return(newExplainCandidates(), nil).Once()
+
+	explanations, err := Explain(client, nil, &dockerclient.Event{Type: "container", Action: "start",
+		Actor: dockerclient.Actor{Attributes: map[string]string{container.TugbotCreatedFrom: "a-start"}}})
+
+	assert.NoError(t, err)
+	for _, explanation := range explanations {
+		assert.False(t, explanation.Match)
+		assert.Equal(t, "events of containers created by tugbot are ignored", explanation.Reason)
+	}
+	client.AssertExpectations(t)
+}
+
+func TestExplain_Error(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, errors.New("no docker")).Once()
+
+	_, err := Explain(client, nil, &dockerclient.Event{})
+
+	assert.EqualError(t, err, "no docker")
+	client.AssertExpectations(t)
+}
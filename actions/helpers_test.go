@@ -0,0 +1,29 @@
+package actions
+
+import (
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// newTestContainer returns a container with id (also used as its name), labels and state.
+func newTestContainer(id string, labels map[string]string, state *dockerclient.State) container.Container {
+	l := make(map[string]string, len(labels))
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	return *container.NewContainer(
+		&dockerclient.ContainerInfo{Id: id, Name: "/" + id, Config: &dockerclient.ContainerConfig{Labels: l}, State: state},
+		nil,
+	)
+}
+
+// newTestCandidate returns an exited test container candidate with id (also used as its name) and labels.
+func newTestCandidate(id string, labels map[string]string) container.Container {
+	l := map[string]string{container.TugbotTest: "true"}
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	return newTestContainer(id, l, stateExited)
+}
@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"sync"
+
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// historySize is the number of recent test container runs kept by Runner
+const historySize = 100
+
+// history keeps copies of recent test container runs.
+type history struct {
+	mu   sync.Mutex
+	runs []container.RunResult
+}
+
+// record adds a copy of run to history, or updates it if the run is already recorded.
+func (h *history) record(run *container.RunResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		if h.runs[i].ID == run.ID {
+			h.runs[i] = *run
+			return
+		}
+	}
+	h.runs = append(h.runs, *run)
+	if len(h.runs) > historySize {
+		h.runs = h.runs[len(h.runs)-historySize:]
+	}
+}
+
+// list returns recent runs, newest first.
+func (h *history) list() []container.RunResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ret := make([]container.RunResult, len(h.runs))
+	for i, run := range h.runs {
+		ret[len(h.runs)-1-i] = run
+	}
+
+	return ret
+}
@@ -0,0 +1,38 @@
+package actions
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	h := &history{}
+	first := &container.RunResult{ID: "1"}
+	second := &container.RunResult{ID: "2"}
+	h.record(first)
+	h.record(second)
+	first.FinishedAt = time.Now()
+	h.record(first)
+
+	runs := h.list()
+	assert.Len(t, runs, 2)
+	assert.Equal(t, "2", runs[0].ID)
+	assert.Equal(t, "1", runs[1].ID)
+	assert.True(t, runs[1].Finished())
+}
+
+func TestHistory_Size(t *testing.T) {
+	h := &history{}
+	for i := 0; i < historySize+10; i++ {
+		h.record(&container.RunResult{ID: fmt.Sprint(i)})
+	}
+
+	runs := h.list()
+	assert.Len(t, runs, historySize)
+	assert.Equal(t, fmt.Sprint(historySize+9), runs[0].ID)
+	assert.Equal(t, "10", runs[historySize-1].ID)
+}
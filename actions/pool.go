@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// pool limits the number of concurrently running test containers; runs submitted
+// when all slots are taken wait in a priority queue (FIFO for the same priority).
+type pool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	queue   runQueue
+	seq     int
+}
+
+// newPool returns a new pool with size slots, zero size means no limit.
+func newPool(size int) *pool {
+	return &pool{size: size}
+}
+
+// submit acquires a slot for run and returns true, otherwise queues run by its test
+// container priority and returns false with current queue depth.
+func (p *pool) submit(run pendingRun) (bool, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.size <= 0 || p.running < p.size {
+		p.running++
+		return true, len(p.queue)
+	}
+	p.seq++
+	heap.Push(&p.queue, &queuedRun{pendingRun: run, priority: run.c.GetPriority(), seq: p.seq})
+
+	return false, len(p.queue)
+}
+
+// release releases a slot. If a run is waiting in the queue, the slot is handed
+// over to it and the run is returned with remaining queue depth.
+func (p *pool) release() (pendingRun, int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		p.running--
+		return pendingRun{}, 0, false
+	}
+	next := heap.Pop(&p.queue).(*queuedRun)
+
+	return next.pendingRun, len(p.queue), true
+}
+
+// replace replaces queued run of test container candidateID with run, keeping its queue
+// position, and returns the replaced run and true, otherwise (no run of the test container
+// is queued) false.
+func (p *pool) replace(candidateID string, run pendingRun) (pendingRun, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, queued := range p.queue {
+		if queued.c.ID() == candidateID {
+			replaced := queued.pendingRun
+			queued.pendingRun = run
+			queued.priority = run.c.GetPriority()
+			heap.Fix(&p.queue, i)
+			return replaced, true
+		}
+	}
+
+	return pendingRun{}, false
+}
+
+type queuedRun struct {
+	pendingRun
+	priority int
+	seq      int
+}
+
+// runQueue implements heap.Interface, higher priority first
+type runQueue []*queuedRun
+
+func (q runQueue) Len() int { return len(q) }
+func (q runQueue) Less(i, j int) bool {
+	if q[i].priority == q[j].priority {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].priority > q[j].priority
+}
+func (q runQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *runQueue) Push(x interface{}) { *q = append(*q, x.(*queuedRun)) }
+func (q *runQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
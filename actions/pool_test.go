@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPriorityRun(name string, priority string) pendingRun {
+	labels := map[string]string{}
+	if priority != "" {
+		labels[container.TugbotPriority] = priority
+	}
+
+	return pendingRun{
+		c: *container.NewContainer(
+			&dockerclient.ContainerInfo{Id: name, Name: name, Config: &dockerclient.ContainerConfig{Labels: labels}},
+			nil,
+		),
+		run: container.NewRunResult(container.TriggerTimer, nil),
+	}
+}
+
+func TestPool_Unlimited(t *testing.T) {
+	p := newPool(0)
+	for i := 0; i < 100; i++ {
+		ok, _ := p.submit(newPriorityRun("c", ""))
+		assert.True(t, ok)
+	}
+}
+
+func TestPool_Priority(t *testing.T) {
+	p := newPool(1)
+	ok, depth := p.submit(newPriorityRun("running", ""))
+	assert.True(t, ok)
+	assert.Equal(t, 0, depth)
+	for i, run := range []pendingRun{
+		newPriorityRun("low", "-1"),
+		newPriorityRun("default1", ""),
+		newPriorityRun("high", "10"),
+		newPriorityRun("default2", "0"),
+	} {
+		ok, depth = p.submit(run)
+		assert.False(t, ok)
+		assert.Equal(t, i+1, depth)
+	}
+
+	var order []string
+	for {
+		next, _, ok := p.release()
+		if !ok {
+			break
+		}
+		order = append(order, next.c.Name())
+	}
+	assert.Equal(t, []string{"high", "default1", "default2", "low"}, order)
+
+	// slot is free after the last release
+	ok, _ = p.submit(newPriorityRun("next", ""))
+	assert.True(t, ok)
+}
+
+func TestPool_Replace(t *testing.T) {
+	p := newPool(1)
+	p.submit(newPriorityRun("running", ""))
+	p.submit(newPriorityRun("first", ""))
+	p.submit(newPriorityRun("second", ""))
+	replacement := newPriorityRun("first", "")
+
+	replaced, ok := p.replace("first", replacement)
+	assert.True(t, ok)
+	assert.Equal(t, "first", replaced.c.ID())
+	assert.False(t, replaced.run == replacement.run)
+	_, ok = p.replace("running", newPriorityRun("running", ""))
+	assert.False(t, ok)
+	next, depth, ok := p.release()
+	assert.True(t, ok)
+	assert.Equal(t, 1, depth)
+	assert.True(t, replacement.run == next.run)
+}
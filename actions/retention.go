@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// Retention removes containers created by tugbot from a test container, keeping
+// only last keepLast containers and containers finished during last keepFor.
+// Zero keepLast or keepFor means no limit. Both can be overwritten per test
+// container by 'tugbot-keep-last' and 'tugbot-keep-for' labels.
+type Retention struct {
+	client   container.Client
+	keepLast int
+	keepFor  time.Duration
+}
+
+// NewRetention returns a new Retention with global (default) retention policy.
+func NewRetention(client container.Client, keepLast int, keepFor time.Duration) *Retention {
+	return &Retention{client: client, keepLast: keepLast, keepFor: keepFor}
+}
+
+// Cleanup removes containers created by tugbot from the same test container as run.
+// Cleanup should be registered as ResultHandler after test results collection.
+func (r *Retention) Cleanup(run *container.RunResult) {
+	if run.ContainerID == "" {
+		return
+	}
+	tc, err := r.client.Inspect(run.ContainerID)
+	if err != nil {
+		log.Errorf("Failed to apply retention policy for %s (%v)", run.CreatedFrom, err)
+		return
+	}
+	keepLast, keepFor := r.policy(*tc)
+	if keepLast == 0 && keepFor == 0 {
+		return
+	}
+	created, err := r.client.ListContainers(func(c container.Container) bool {
+		return c.CreatedFrom() == run.CreatedFrom && !c.IsRunning()
+	})
+	if err != nil {
+		log.Errorf("Failed to list containers created from %s (%v)", run.CreatedFrom, err)
+		return
+	}
+	for _, c := range expired(created, keepLast, keepFor, time.Now()) {
+		if err := r.client.RemoveContainer(c.ID()); err != nil {
+			log.Errorf("Failed to remove container %s (%v)", c.Name(), err)
+		} else {
+			log.Infof("Removed container %s (%s) created from %s", c.Name(), c.ID(), run.CreatedFrom)
+		}
+	}
+}
+
+func (r *Retention) policy(c container.Container) (int, time.Duration) {
+	keepLast := r.keepLast
+	if val, ok := c.GetKeepLast(); ok {
+		keepLast = val
+	}
+	keepFor := r.keepFor
+	if val, ok := c.GetKeepFor(); ok {
+		keepFor = val
+	}
+
+	return keepLast, keepFor
+}
+
+// expired returns containers that should be removed according to retention policy.
+func expired(containers []container.Container, keepLast int, keepFor time.Duration, now time.Time) []container.Container {
+	sort.Sort(byFinishedAtDesc(containers))
+	ret := []container.Container{}
+	for i, c := range containers {
+		if (keepLast > 0 && i >= keepLast) || (keepFor > 0 && now.Sub(c.FinishedAt()) > keepFor) {
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
+
+type byFinishedAtDesc []container.Container
+
+func (s byFinishedAtDesc) Len() int           { return len(s) }
+func (s byFinishedAtDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFinishedAtDesc) Less(i, j int) bool { return s[i].FinishedAt().After(s[j].FinishedAt()) }
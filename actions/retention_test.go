@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newCreatedRun(id string) *container.RunResult {
+	run := container.NewRunResult(container.TriggerTimer, nil)
+	run.ContainerID = id
+	run.CreatedFrom = "c"
+
+	return run
+}
+
+func TestRetentionCleanup_KeepLastLabel(t *testing.T) {
+	now := time.Now()
+	c1 := newTestContainer("1", map[string]string{container.TugbotCreatedFrom: "c", container.TugbotKeepLast: "2"}, &dockerclient.State{FinishedAt: now})
+	c2 := newTestContainer("2", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now.Add(-time.Minute)})
+	c3 := newTestContainer("3", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now.Add(-time.Hour)})
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "1").Return(&c1, nil).Once()
+	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
+		Run(func(args mock.Arguments) {
+			filter := args.Get(0).(container.Filter)
+			assert.True(t, filter(c2))
+			assert.False(t, filter(newTestContainer("4", map[string]string{container.TugbotCreatedFrom: "other"}, &dockerclient.State{})))
+		}).Return([]container.Container{c3, c1, c2}, nil).Once()
+	client.On("RemoveContainer", "3").Return(nil).Once()
+
+	NewRetention(client, 10, 0).Cleanup(newCreatedRun("1"))
+
+	client.AssertExpectations(t)
+}
+
+func TestRetentionCleanup_KeepFor(t *testing.T) {
+	now := time.Now()
+	c1 := newTestContainer("1", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now})
+	c2 := newTestContainer("2", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now.Add(-time.Hour * 2)})
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "1").Return(&c1, nil).Once()
+	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).Return([]container.Container{c1, c2}, nil).Once()
+	client.On("RemoveContainer", "2").Return(errors.New("whoops")).Once()
+
+	NewRetention(client, 0, time.Hour).Cleanup(newCreatedRun("1"))
+
+	client.AssertExpectations(t)
+}
+
+func TestRetentionCleanup_NoLimit(t *testing.T) {
+	c1 := newTestContainer("1", map[string]string{container.TugbotCreatedFrom: "c", container.TugbotKeepLast: "0"}, &dockerclient.State{FinishedAt: time.Now()})
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "1").Return(&c1, nil).Once()
+
+	NewRetention(client, 5, 0).Cleanup(newCreatedRun("1"))
+
+	client.AssertExpectations(t)
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	c1 := newTestContainer("1", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now})
+	c2 := newTestContainer("2", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now.Add(-time.Minute)})
+	c3 := newTestContainer("3", map[string]string{container.TugbotCreatedFrom: "c"}, &dockerclient.State{FinishedAt: now.Add(-time.Hour * 25)})
+
+	assert.Equal(t, []container.Container{c2, c3}, expired([]container.Container{c3, c2, c1}, 1, 0, now))
+	assert.Equal(t, []container.Container{c3}, expired([]container.Container{c3, c2, c1}, 0, time.Hour*24, now))
+	assert.Empty(t, expired([]container.Container{c3, c2, c1}, 3, 0, now))
+}
@@ -10,17 +10,20 @@ import (
 // Run looks at Docker containers to see if any of the images
 // used to start those containers is a test container.
 // For each test container it'll create and start a new container according
-// to tugbots' labels.
-func Run(client container.Client, names []string, e *dockerclient.Event) error {
+// to tugbots' labels and hand it off to runner for tracking.
+func Run(runner *Runner, names []string, e *dockerclient.Event) error {
 	var ec common.ErrorBuilder
+	if isContainerDestroyed(e) {
+		runner.discovery.forget(e.Actor.ID)
+	}
 	if !container.IsSwarmTask(e) && !container.IsCreatedByTugbot(e) {
-		candidates, err := client.ListContainers(containerFilter(names))
+		candidates, err := runner.client.ListContainers(containerFilter(names))
 		if err != nil {
 			ec.Append(err)
 		} else {
 			for _, currCandidate := range candidates {
 				if currCandidate.IsEventListener(e) {
-					if err := client.StartContainerFrom(currCandidate); err != nil {
+					if err := runner.StartContainerFromEvent(currCandidate, e); failedToStart(err) {
 						log.Error(err)
 						ec.Append(err)
 					}
@@ -32,6 +35,11 @@ func Run(client container.Client, names []string, e *dockerclient.Event) error {
 	return ec.ToError()
 }
 
+// isContainerDestroyed returns whether or not e is a container destroy event.
+func isContainerDestroyed(e *dockerclient.Event) bool {
+	return e.Type == "container" && e.Action == "destroy"
+}
+
 func containerFilter(names []string) container.Filter {
 	return func(c container.Container) bool {
 		return nameFilter(names)(c) && c.IsTugbotCandidate()
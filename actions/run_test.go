@@ -34,12 +34,16 @@ func TestRun_StartEvent(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil)
-	client.On("StartContainerFrom", mock.AnythingOfType("container.Container")).
+	client.On("StartContainerFrom", mock.AnythingOfType("container.Container"), mock.AnythingOfType("*container.RunResult")).
 		Run(func(args mock.Arguments) {
 			assert.Equal(t, c.Name(), args.Get(0).(container.Container).Name())
+			assert.Equal(t, container.TriggerDocker, args.Get(1).(*container.RunResult).Trigger)
 		}).Return(nil)
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&c, nil)
 
-	err := Run(client, []string{}, &dockerclient.Event{Type: "container", Action: "start"})
+	runner := NewRunner(client, RunnerConfig{})
+	err := Run(runner, []string{}, &dockerclient.Event{Type: "container", Action: "start"})
+	runner.Wait()
 	assert.NoError(t, err)
 	client.AssertExpectations(t)
 }
@@ -78,9 +82,12 @@ func TestRun_CallByEventType(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c1, c2}, nil)
-	client.On("StartContainerFrom", c2).Return(nil)
+	client.On("StartContainerFrom", c2, mock.AnythingOfType("*container.RunResult")).Return(nil)
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&c2, nil)
 
-	err := Run(client, []string{c1.Name(), c2.Name()}, &dockerclient.Event{Type: "container", Action: "start"})
+	runner := NewRunner(client, RunnerConfig{})
+	err := Run(runner, []string{c1.Name(), c2.Name()}, &dockerclient.Event{Type: "container", Action: "start"})
+	runner.Wait()
 
 	assert.NoError(t, err)
 	client.AssertExpectations(t)
@@ -91,16 +98,30 @@ func TestRun_EventCreatedByTugbot(t *testing.T) {
 
 	attributes := map[string]string{container.TugbotTest: "true",
 		container.TugbotCreatedFrom: "aabb"}
-	err := Run(client, []string{}, &dockerclient.Event{Status: "start",
+	err := Run(NewRunner(client, RunnerConfig{}), []string{}, &dockerclient.Event{Status: "start",
 		Actor: dockerclient.Actor{Attributes: attributes}})
 	assert.NoError(t, err)
 	client.AssertExpectations(t)
 }
 
+func TestRun_DestroyEventForgetsCandidate(t *testing.T) {
+	c := newTestCandidate("cid", map[string]string{container.TugbotEventDiscovered: ""})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil)
+	runner := NewRunner(client, RunnerConfig{})
+	runner.discovery.discover([]container.Container{c})
+
+	err := Run(runner, []string{}, &dockerclient.Event{Type: "container", Action: "destroy", Actor: dockerclient.Actor{ID: c.ID()}})
+
+	assert.NoError(t, err)
+	assert.Empty(t, runner.discovery.known)
+	client.AssertExpectations(t)
+}
+
 func TestRun_NoCandidates(t *testing.T) {
 	client := mockclient.NewMockClient()
 	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil)
-	err := Run(client, []string{}, &dockerclient.Event{Status: "start"})
+	err := Run(NewRunner(client, RunnerConfig{}), []string{}, &dockerclient.Event{Status: "start"})
 	assert.NoError(t, err)
 	client.AssertExpectations(t)
 }
@@ -109,7 +130,7 @@ func TestRun_ErrorListContainers(t *testing.T) {
 	client := mockclient.NewMockClient()
 	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, errors.New("whoops"))
 
-	err := Run(client, []string{}, &dockerclient.Event{Status: "start"})
+	err := Run(NewRunner(client, RunnerConfig{}), []string{}, &dockerclient.Event{Status: "start"})
 	assert.Error(t, err)
 	assert.EqualError(t, err, "whoops")
 	client.AssertExpectations(t)
@@ -134,9 +155,9 @@ func TestRun_ErrorStartContainerFrom(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil)
-	client.On("StartContainerFrom", mock.AnythingOfType("container.Container")).Return(errors.New("whoops"))
+	client.On("StartContainerFrom", mock.AnythingOfType("container.Container"), mock.AnythingOfType("*container.RunResult")).Return(errors.New("whoops"))
 
-	err := Run(client, []string{}, &dockerclient.Event{Type: "container", Action: "start"})
+	err := Run(NewRunner(client, RunnerConfig{}), []string{}, &dockerclient.Event{Type: "container", Action: "start"})
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "whoops")
@@ -211,7 +232,7 @@ func TestRun_EventSwarmTask(t *testing.T) {
 	client := mockclient.NewMockClient()
 
 	attributes := map[string]string{container.SwarmTaskID: "123hh"}
-	err := Run(client, []string{}, &dockerclient.Event{Status: "start",
+	err := Run(NewRunner(client, RunnerConfig{}), []string{}, &dockerclient.Event{Status: "start",
 		Actor: dockerclient.Actor{Attributes: attributes}})
 	assert.NoError(t, err)
 	client.AssertExpectations(t)
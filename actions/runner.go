@@ -0,0 +1,443 @@
+package actions
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// stopTimeout is the time to wait for a replaced or timed out test container to stop before killing it
+const stopTimeout = time.Second * 10
+
+// ErrSkipped is returned when a test container run is skipped by 'skip' concurrency policy,
+// because the test container is already running.
+var ErrSkipped = errors.New("test container is already running, run skipped")
+
+// ErrDryRun is returned when a test container run is not started in dry run mode.
+var ErrDryRun = errors.New("dry run, not started")
+
+// RunnerConfig is the Runner configuration.
+type RunnerConfig struct {
+	// MaxConcurrent is the max number of test containers running at once, zero for no limit
+	MaxConcurrent int
+	// Timeout is the default run timeout (overridden by 'tugbot-timeout' label), zero for no timeout
+	Timeout time.Duration
+	// Events is called with tugbot lifecycle events of each test container run, nil to disable
+	Events EventHandler
+	// DryRun logs triggered test container runs instead of starting them
+	DryRun bool
+}
+
+// ResultHandler is called with the result of each finished test container run.
+type ResultHandler func(*container.RunResult)
+
+// EventHandler is called with a tugbot test container run lifecycle event.
+type EventHandler func(*dockerclient.Event)
+
+// Runner starts test containers and tracks each run until the test container exits.
+// Runner allows a single run per test container at a time, overlapping runs are
+// handled according to the test container concurrency policy. The number of test
+// containers running at once is bounded by the Runner pool, runs exceeding it are
+// queued by test container priority.
+type Runner struct {
+	client   container.Client
+	timeout  time.Duration
+	handlers []ResultHandler
+	events   EventHandler
+	dryRun   bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	stopped  bool
+	active   map[string]*activeRun
+	debounce *debouncer
+	pool     *pool
+	history  *history
+	// discovery is shared by the ticker, that discovers candidates, and Run, that forgets destroyed ones
+	discovery *discovery
+}
+
+// activeRun is a run lock of a test container, with at most one pending run to start
+// after the current one is finished
+type activeRun struct {
+	containerID string
+	stop        bool
+	pending     *pendingRun
+	// retry is the backoff timer of the next attempt after the failed last attempt,
+	// while the retry is waiting
+	retry *time.Timer
+	last  pendingRun
+}
+
+// setPending sets run of test container c as the pending run, returning the replaced pending run, if any.
+func (a *activeRun) setPending(c container.Container, run *container.RunResult) []*container.RunResult {
+	var ret []*container.RunResult
+	if a.pending != nil {
+		a.pending.run.Dropped = container.StatusReplaced
+		ret = append(ret, a.pending.run)
+	}
+	a.pending = &pendingRun{c: c, run: run}
+
+	return ret
+}
+
+type pendingRun struct {
+	c   container.Container
+	run *container.RunResult
+}
+
+// NewRunner returns a new Runner configured by config, that hands off run results to handlers.
+func NewRunner(client container.Client, config RunnerConfig, handlers ...ResultHandler) *Runner {
+	return &Runner{
+		client:    client,
+		timeout:   config.Timeout,
+		handlers:  handlers,
+		events:    config.Events,
+		dryRun:    config.DryRun,
+		active:    make(map[string]*activeRun),
+		debounce:  newDebouncer(),
+		pool:      newPool(config.MaxConcurrent),
+		history:   &history{},
+		discovery: newDiscovery(),
+	}
+}
+
+// StartContainerFrom creates and starts a new test container from candidate c and
+// waits in background for it to exit. Returns ErrSkipped if the run is skipped by
+// concurrency policy or ErrDryRun in dry run mode, the run is not created then.
+func (r *Runner) StartContainerFrom(c container.Container, run *container.RunResult) error {
+	run.CreatedFrom = c.Name()
+	if r.dryRun {
+		log.Infof("Dry run: %s run of %s is not started (Container ID: %s)", run.Trigger, c.Name(), c.ID())
+		return ErrDryRun
+	}
+	r.emit(container.EventTestTriggered, run)
+	r.mu.Lock()
+	if current, ok := r.active[c.ID()]; ok {
+		dropped, err := r.overlap(current, c, run)
+		r.mu.Unlock()
+		r.drop(dropped)
+		return err
+	}
+	r.active[c.ID()] = &activeRun{}
+	r.mu.Unlock()
+
+	return r.schedule(c, run)
+}
+
+// StartContainerFromEvent starts a new test container from candidate c triggered by Docker event e.
+// If test container has debounce window, all events triggering it during the window are collapsed
+// into a single run, started when the window is closed.
+func (r *Runner) StartContainerFromEvent(c container.Container, e *dockerclient.Event) error {
+	window, ok := c.GetEventDebounce()
+	if !ok || window <= 0 {
+		return r.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, e))
+	}
+	r.wg.Add(1)
+	if !r.debounce.add(c, e, window, r.startDebounced) {
+		log.Debugf("Event coalesced into pending run of %s (%+v)", c.Name(), e)
+		r.wg.Done()
+	}
+
+	return nil
+}
+
+func (r *Runner) startDebounced(c container.Container, events []*dockerclient.Event) {
+	defer r.wg.Done()
+	run := container.NewRunResult(container.TriggerDocker, events[len(events)-1])
+	run.Events = events
+	log.Infof("Starting %s triggered by %d coalesced events", c.Name(), len(events))
+	if err := r.StartContainerFrom(c, run); failedToStart(err) {
+		log.Errorf("Failed to start %s (%v)", c.Name(), err)
+	}
+}
+
+// Stop cancels pending debounced runs and retries waiting for their backoff, so no new
+// test container is started after Docker events monitoring is stopped.
+func (r *Runner) Stop() {
+	for _, c := range r.debounce.stop() {
+		log.Infof("Dropping pending debounced run of %s", c.Name())
+		r.wg.Done()
+	}
+	r.mu.Lock()
+	r.stopped = true
+	var abandoned []pendingRun
+	for _, current := range r.active {
+		if current.retry != nil && current.retry.Stop() {
+			current.retry = nil
+			abandoned = append(abandoned, current.last)
+		}
+	}
+	r.mu.Unlock()
+	for _, last := range abandoned {
+		log.Infof("Retry of %s canceled, tugbot is stopping (Attempt: %d)", last.c.Name(), last.run.Attempt+1)
+		r.abandon(last.c, last.run)
+		r.wg.Done()
+	}
+}
+
+// Runs returns copies of recent test container runs, newest first.
+func (r *Runner) Runs() []container.RunResult {
+	return r.history.list()
+}
+
+// Wait blocks until all tracked test container runs are finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// overlap applies concurrency policy on a run of already running test container c, must be called under lock.
+// Returns runs dropped by the policy and ErrSkipped if the run is skipped.
+func (r *Runner) overlap(current *activeRun, c container.Container, run *container.RunResult) ([]*container.RunResult, error) {
+	var dropped []*container.RunResult
+	switch c.GetConcurrencyPolicy() {
+	case container.ConcurrencyQueue:
+		dropped = current.setPending(c, run)
+		if len(dropped) > 0 {
+			log.Infof("Test container %s is already running, replacing queued run (Trigger: %s)", c.Name(), run.Trigger)
+		} else {
+			log.Infof("Test container %s is already running, run queued (Trigger: %s)", c.Name(), run.Trigger)
+		}
+	case container.ConcurrencyReplace:
+		if current.containerID == "" {
+			if replaced, ok := r.pool.replace(c.ID(), pendingRun{c: c, run: run}); ok {
+				// current run is waiting for a pool slot, replace it before it starts
+				log.Infof("Test container %s run is queued, replacing queued run (Trigger: %s)", c.Name(), run.Trigger)
+				replaced.run.Dropped = container.StatusReplaced
+				return []*container.RunResult{replaced.run}, nil
+			}
+		}
+		dropped = current.setPending(c, run)
+		log.Infof("Test container %s is already running, replacing run (Trigger: %s)", c.Name(), run.Trigger)
+		if !current.stop {
+			current.stop = true
+			if current.containerID != "" {
+				go r.stop(current.containerID)
+			}
+		}
+	default:
+		log.Infof("Test container %s is already running, skipping run (Trigger: %s)", c.Name(), run.Trigger)
+		run.Dropped = container.StatusSkipped
+		return []*container.RunResult{run}, ErrSkipped
+	}
+
+	return dropped, nil
+}
+
+// drop emits terminal lifecycle events of runs dropped by concurrency policy before they started.
+func (r *Runner) drop(runs []*container.RunResult) {
+	for _, run := range runs {
+		action := container.EventTestReplaced
+		if run.Dropped == container.StatusSkipped {
+			action = container.EventTestSkipped
+		}
+		r.emit(action, run)
+	}
+}
+
+// schedule starts run of test container c if a pool slot is free, otherwise queues it
+func (r *Runner) schedule(c container.Container, run *container.RunResult) error {
+	if ok, depth := r.pool.submit(pendingRun{c: c, run: run}); !ok {
+		log.Infof("Max concurrent tests reached, %s run queued (Trigger: %s, Priority: %d, Queue depth: %d)",
+			c.Name(), run.Trigger, c.GetPriority(), depth)
+		return nil
+	}
+
+	return r.start(c, run)
+}
+
+func (r *Runner) start(c container.Container, run *container.RunResult) error {
+	run.CreatedFrom = c.Name()
+	if err := r.client.StartContainerFrom(c, run); err != nil {
+		run.Error = err.Error()
+		run.FinishedAt = time.Now()
+		run.Final = true
+		r.history.record(run)
+		r.emit(container.EventTestFailedToStart, run)
+		r.done(c.ID())
+		return err
+	}
+	r.history.record(run)
+	r.emit(container.EventTestStarted, run)
+	r.mu.Lock()
+	current := r.active[c.ID()]
+	current.containerID = run.ContainerID
+	if current.stop {
+		go r.stop(run.ContainerID)
+	}
+	r.mu.Unlock()
+	timeout := r.timeout
+	if t, ok := c.GetTimeout(); ok {
+		timeout = t
+	}
+	r.wg.Add(1)
+	go func() {
+		if r.track(c, run, timeout) {
+			r.retry(c, run)
+		} else {
+			r.done(c.ID())
+		}
+		r.wg.Done()
+	}()
+
+	return nil
+}
+
+// stop stops the container, killing it if it fails to stop.
+func (r *Runner) stop(containerID string) {
+	if err := r.client.StopContainer(containerID, stopTimeout); err != nil {
+		log.Errorf("Failed to stop container %s, killing it (%v)", containerID, err)
+		if err := r.client.KillContainer(containerID); err != nil {
+			log.Errorf("Failed to kill container %s (%v)", containerID, err)
+		}
+	}
+}
+
+// retry releases pool slot of failed run of test container c and schedules the next attempt
+// after backoff; test container run lock is kept until the last attempt is finished.
+func (r *Runner) retry(c container.Container, run *container.RunResult) {
+	backoff := c.GetRetryBackoff(run.Attempt)
+	next := run.Retry()
+	log.Infof("Retrying %s in %s (Attempt: %d, Previous attempt status: %s)", c.Name(), backoff, next.Attempt, run.Status())
+	r.wg.Add(1)
+	r.mu.Lock()
+	current := r.active[c.ID()]
+	current.containerID = ""
+	current.last = pendingRun{c: c, run: run}
+	current.retry = time.AfterFunc(backoff, func() {
+		defer r.wg.Done()
+		r.mu.Lock()
+		current.retry = nil
+		stop := current.stop || r.stopped
+		r.mu.Unlock()
+		if stop {
+			log.Infof("Retry of %s canceled (Attempt: %d)", c.Name(), next.Attempt)
+			r.abandon(c, run)
+			return
+		}
+		if err := r.schedule(c, next); err != nil {
+			log.Errorf("Failed to start retry of %s (%v)", c.Name(), err)
+		}
+	})
+	r.mu.Unlock()
+	r.releaseSlot()
+}
+
+// abandon finishes test container c runs with the last attempt run, when its retry is
+// canceled by concurrency policy or Stop.
+func (r *Runner) abandon(c container.Container, run *container.RunResult) {
+	run.Final = true
+	log.Infof("Test container %s final status: %s (Attempts: %d)", c.Name(), run.Status(), run.Attempt)
+	r.history.record(run)
+	r.emit(container.EventTestFinished, run)
+	r.release(c.ID())
+}
+
+// done releases pool slot and run lock of test container, starting next queued runs.
+func (r *Runner) done(candidateID string) {
+	r.releaseSlot()
+	r.release(candidateID)
+}
+
+// releaseSlot releases pool slot and starts next queued run, if any.
+func (r *Runner) releaseSlot() {
+	if next, depth, ok := r.pool.release(); ok {
+		log.Infof("Starting queued run of %s (Trigger: %s, Queue depth: %d)", next.c.Name(), next.run.Trigger, depth)
+		if err := r.start(next.c, next.run); err != nil {
+			log.Errorf("Failed to start queued run of %s (%v)", next.c.Name(), err)
+		}
+	}
+}
+
+// release releases test container run lock and schedules next pending run, if any.
+func (r *Runner) release(candidateID string) {
+	r.mu.Lock()
+	current := r.active[candidateID]
+	if current.pending == nil {
+		delete(r.active, candidateID)
+		r.mu.Unlock()
+		return
+	}
+	next := current.pending
+	r.active[candidateID] = &activeRun{}
+	r.mu.Unlock()
+	if err := r.schedule(next.c, next.run); err != nil {
+		log.Errorf("Failed to start pending run of %s (%v)", next.c.Name(), err)
+	}
+}
+
+// track waits for the test container run to finish, stopping it after timeout (if positive),
+// and hands off the run result to handlers. track returns true if the run should be retried.
+func (r *Runner) track(candidate container.Container, run *container.RunResult, timeout time.Duration) bool {
+	var timer *time.Timer
+	expired := make(chan struct{})
+	if timeout > 0 {
+		containerID, containerName := run.ContainerID, run.ContainerName
+		timer = time.AfterFunc(timeout, func() {
+			close(expired)
+			log.Warnf("Test container %s (%s) timed out after %s, stopping it", containerName, containerID, timeout)
+			r.stop(containerID)
+		})
+	}
+	c, err := r.client.WaitContainer(run.ContainerID)
+	if timer != nil && !timer.Stop() {
+		<-expired
+		run.TimedOut = true
+	}
+	if err != nil {
+		log.Errorf("Failed waiting for test container %s (%s) to exit (%v)", run.ContainerName, run.ContainerID, err)
+		run.Error = err.Error()
+		run.FinishedAt = time.Now()
+	} else {
+		run.ExitCode = c.ExitCode()
+		run.StartedAt = c.StartedAt()
+		run.FinishedAt = c.FinishedAt()
+		log.Infof("Test container %s (%s) exited with code %d (Status: %s, Duration: %s, Trigger: %s)",
+			run.ContainerName, run.ContainerID, run.ExitCode, run.Status(), run.Duration(), run.Trigger)
+	}
+	retry := r.retryable(candidate, run)
+	run.Final = !retry
+	if run.Final && run.Attempt > 1 {
+		log.Infof("Test container %s final status: %s (Attempts: %d)", candidate.Name(), run.Status(), run.Attempt)
+	}
+	r.history.record(run)
+	if run.TimedOut {
+		r.emit(container.EventTestTimeout, run)
+	}
+	r.emit(container.EventTestFinished, run)
+	for _, handle := range r.handlers {
+		handle(run)
+	}
+
+	return retry
+}
+
+// emit hands off lifecycle event with action about the run to events handler, if any.
+func (r *Runner) emit(action string, run *container.RunResult) {
+	if r.events != nil {
+		r.events(container.NewRunEvent(action, *run))
+	}
+}
+
+// retryable returns whether or not a finished run of test container c should be retried:
+// run failed (exited with non zero exit code), retry count is not exceeded, run was not replaced
+// and Runner is not stopped.
+func (r *Runner) retryable(c container.Container, run *container.RunResult) bool {
+	count, ok := c.GetRetryCount()
+	if !ok || run.Attempt > count || run.Error != "" || run.Passed() {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.stopped && !r.active[c.ID()].stop
+}
+
+// failedToStart returns whether or not StartContainerFrom error err is a failure to start a run,
+// runs not started on purpose (skipped or dry run) are not failures.
+func failedToStart(err error) bool {
+	return err != nil && err != ErrSkipped && err != ErrDryRun
+}
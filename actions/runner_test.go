@@ -0,0 +1,517 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunnerStartContainerFrom(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	finished := time.Now()
+	exited := *container.NewContainer(
+		&dockerclient.ContainerInfo{
+			Id:    "created",
+			State: &dockerclient.State{ExitCode: 2, StartedAt: finished.Add(-time.Minute), FinishedAt: finished},
+		},
+		nil,
+	)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "created"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "created").Return(&exited, nil).Once()
+
+	var results []*container.RunResult
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) {
+		results = append(results, r)
+	})
+	e := &dockerclient.Event{Type: "container", Action: "start"}
+	err := runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, e))
+	runner.Wait()
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 2, results[0].ExitCode)
+	assert.Equal(t, finished, results[0].FinishedAt)
+	assert.Equal(t, time.Minute, results[0].Duration())
+	assert.Equal(t, e, results[0].Event)
+	runs := runner.Runs()
+	assert.Len(t, runs, 1)
+	assert.Equal(t, container.StatusFailed, runs[0].Status())
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_StartError(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).Return(errors.New("whoops")).Once()
+
+	called := false
+	runner := NewRunner(client, RunnerConfig{}, func(*container.RunResult) { called = true })
+	err := runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil))
+	runner.Wait()
+
+	assert.EqualError(t, err, "whoops")
+	assert.False(t, called)
+	assert.Equal(t, container.StatusError, runner.Runs()[0].Status())
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_DryRun(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	client := mockclient.NewMockClient()
+
+	var events []*dockerclient.Event
+	runner := NewRunner(client, RunnerConfig{DryRun: true, Events: func(e *dockerclient.Event) { events = append(events, e) }})
+	err := runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil))
+	runner.Wait()
+
+	assert.Equal(t, ErrDryRun, err)
+	assert.Empty(t, events)
+	assert.Empty(t, runner.Runs())
+	client.AssertNotCalled(t, "StartContainerFrom", mock.Anything, mock.Anything)
+}
+
+func TestRunnerStartContainerFrom_Events(t *testing.T) {
+	c := newTestCandidate("c", nil)
+	finished := time.Now()
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{
+		State: &dockerclient.State{ExitCode: 1, StartedAt: finished.Add(-time.Second), FinishedAt: finished},
+	}, nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "created"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "created").Return(&exited, nil).Once()
+
+	var events []*dockerclient.Event
+	runner := NewRunner(client, RunnerConfig{Events: func(e *dockerclient.Event) { events = append(events, e) }})
+	run := container.NewRunResult(container.TriggerCron, nil)
+	assert.NoError(t, runner.StartContainerFrom(c, run))
+	runner.Wait()
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, container.EventTestTriggered, events[0].Action)
+	assert.Equal(t, container.EventTestStarted, events[1].Action)
+	assert.Equal(t, "created", events[1].ID)
+	assert.Equal(t, container.EventTestFinished, events[2].Action)
+	for _, e := range events {
+		assert.Equal(t, container.EventType, e.Type)
+		assert.Equal(t, "c", e.Actor.Attributes["name"])
+		assert.Equal(t, run.ID, e.Actor.Attributes["run-id"])
+		assert.Equal(t, container.TriggerCron, e.Actor.Attributes["trigger"])
+	}
+	assert.Equal(t, "1", events[2].Actor.Attributes["exit-code"])
+	assert.Equal(t, "1s", events[2].Actor.Attributes["duration"])
+	assert.Equal(t, container.StatusFailed, events[2].Actor.Attributes["status"])
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_FailedToStartEvent(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).Return(errors.New("no such image")).Once()
+
+	var events []*dockerclient.Event
+	runner := NewRunner(client, RunnerConfig{Events: func(e *dockerclient.Event) { events = append(events, e) }})
+	assert.Error(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	runner.Wait()
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, container.EventTestTriggered, events[0].Action)
+	assert.Equal(t, container.EventTestFailedToStart, events[1].Action)
+	assert.Equal(t, "no such image", events[1].Actor.Attributes["error"])
+	assert.Equal(t, container.StatusError, events[1].Actor.Attributes["status"])
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_WaitError(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&container.Container{}, errors.New("oops")).Once()
+
+	var result *container.RunResult
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) { result = r })
+	err := runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil))
+	runner.Wait()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "oops", result.Error)
+	assert.True(t, result.Finished())
+	assert.False(t, result.Passed())
+	client.AssertExpectations(t)
+}
+
+// newBlockingClient returns mock client, where the first test container run blocks until release is closed
+func newBlockingClient(c container.Container, release chan struct{}) *mockclient.MockClient {
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "first"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "first").
+		Run(func(args mock.Arguments) {
+			<-release
+		}).Return(&exited, nil).Once()
+
+	return client
+}
+
+// actionRecorder records actions of run events with run status, safe for concurrent use
+type actionRecorder struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (r *actionRecorder) record(e *dockerclient.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions = append(r.actions, e.Action+":"+e.Actor.Attributes["status"])
+}
+
+func (r *actionRecorder) count(action string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := 0
+	for _, curr := range r.actions {
+		if curr == action {
+			ret++
+		}
+	}
+
+	return ret
+}
+
+func TestRunnerStartContainerFrom_ConcurrencySkip(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	release := make(chan struct{})
+	client := newBlockingClient(c, release)
+	events := &actionRecorder{}
+
+	runner := NewRunner(client, RunnerConfig{Events: events.record})
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	assert.Equal(t, ErrSkipped, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	close(release)
+	runner.Wait()
+
+	assert.Equal(t, 1, events.count(container.EventTestSkipped+":"+container.StatusSkipped))
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 1)
+}
+
+func TestRunnerStartContainerFrom_ConcurrencyQueue(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotConcurrency: container.ConcurrencyQueue})
+	release := make(chan struct{})
+	client := newBlockingClient(c, release)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			assert.Equal(t, container.TriggerDocker, args.Get(1).(*container.RunResult).Trigger)
+			args.Get(1).(*container.RunResult).ContainerID = "second"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "second").Return(&exited, nil).Once()
+
+	var triggers []string
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) { triggers = append(triggers, r.Trigger) })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	close(release)
+	runner.Wait()
+
+	assert.Equal(t, []string{container.TriggerTimer, container.TriggerDocker}, triggers)
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_ConcurrencyQueueCoalesced(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotConcurrency: container.ConcurrencyQueue})
+	release := make(chan struct{})
+	client := newBlockingClient(c, release)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			assert.Equal(t, container.TriggerAPI, args.Get(1).(*container.RunResult).Trigger)
+			args.Get(1).(*container.RunResult).ContainerID = "last"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "last").Return(&exited, nil).Once()
+	events := &actionRecorder{}
+
+	runner := NewRunner(client, RunnerConfig{Events: events.record})
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	for _, trigger := range []string{container.TriggerDocker, container.TriggerDocker, container.TriggerAPI} {
+		assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(trigger, nil)))
+	}
+	close(release)
+	runner.Wait()
+
+	// only the last queued run starts
+	assert.Equal(t, 2, events.count(container.EventTestReplaced+":"+container.StatusReplaced))
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 2)
+}
+
+func TestRunnerStartContainerFrom_ConcurrencyReplace(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotConcurrency: container.ConcurrencyReplace})
+	release := make(chan struct{})
+	client := newBlockingClient(c, release)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client.On("StopContainer", "first", stopTimeout).
+		Run(func(args mock.Arguments) {
+			close(release)
+		}).Return(nil).Once()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "third"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "third").Return(&exited, nil).Once()
+	events := &actionRecorder{}
+
+	runner := NewRunner(client, RunnerConfig{Events: events.record})
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	runner.Wait()
+
+	// second run is replaced by the third one before it starts
+	assert.Equal(t, 1, events.count(container.EventTestReplaced+":"+container.StatusReplaced))
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 2)
+}
+
+func TestRunnerStartContainerFrom_ConcurrencyReplaceQueued(t *testing.T) {
+	blocker := newTestCandidate("other", nil)
+	c := newTestCandidate("candidate", map[string]string{container.TugbotConcurrency: container.ConcurrencyReplace})
+	release := make(chan struct{})
+	client := newBlockingClient(blocker, release)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			assert.Equal(t, container.TriggerDocker, args.Get(1).(*container.RunResult).Trigger)
+			args.Get(1).(*container.RunResult).ContainerID = "replacement"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "replacement").Return(&exited, nil).Once()
+	events := &actionRecorder{}
+
+	runner := NewRunner(client, RunnerConfig{MaxConcurrent: 1, Events: events.record})
+	assert.NoError(t, runner.StartContainerFrom(blocker, container.NewRunResult(container.TriggerTimer, nil)))
+	// queued, waiting for a pool slot
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	// replaces the queued run, nothing to stop
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	close(release)
+	runner.Wait()
+
+	client.AssertExpectations(t)
+	assert.Equal(t, 1, events.count(container.EventTestReplaced+":"+container.StatusReplaced))
+	client.AssertNotCalled(t, "StopContainer", mock.Anything, mock.Anything)
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 2)
+}
+
+func TestRunnerStartContainerFrom_MaxConcurrent(t *testing.T) {
+	first := newTestCandidate("candidate", nil)
+	second := newTestCandidate("other", nil)
+	release := make(chan struct{})
+	client := newBlockingClient(first, release)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{}}, nil)
+	client.On("StartContainerFrom", second, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "second"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "second").Return(&exited, nil).Once()
+
+	var started []string
+	runner := NewRunner(client, RunnerConfig{MaxConcurrent: 1}, func(r *container.RunResult) { started = append(started, r.ContainerID) })
+	assert.NoError(t, runner.StartContainerFrom(first, container.NewRunResult(container.TriggerTimer, nil)))
+	assert.NoError(t, runner.StartContainerFrom(second, container.NewRunResult(container.TriggerTimer, nil)))
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 1)
+	close(release)
+	runner.Wait()
+
+	assert.Equal(t, []string{"first", "second"}, started)
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_Timeout(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotTimeout: "10ms"})
+	stopped := make(chan struct{})
+	killed := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{ExitCode: 137, FinishedAt: time.Now()}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "hung"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "hung").
+		Run(func(args mock.Arguments) {
+			<-stopped
+		}).Return(&killed, nil).Once()
+	client.On("StopContainer", "hung", stopTimeout).Return(errors.New("not responding")).Once()
+	client.On("KillContainer", "hung").
+		Run(func(args mock.Arguments) {
+			close(stopped)
+		}).Return(nil).Once()
+
+	var result *container.RunResult
+	var actions []string
+	runner := NewRunner(client, RunnerConfig{Timeout: time.Hour, Events: func(e *dockerclient.Event) {
+		actions = append(actions, e.Action)
+	}}, func(r *container.RunResult) { result = r })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	runner.Wait()
+
+	assert.Equal(t, []string{container.EventTestTriggered, container.EventTestStarted,
+		container.EventTestTimeout, container.EventTestFinished}, actions)
+	assert.True(t, result.TimedOut)
+	assert.Equal(t, container.StatusTimeout, result.Status())
+	assert.False(t, result.Passed())
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_NoTimeout(t *testing.T) {
+	c := newTestCandidate("candidate", nil)
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{FinishedAt: time.Now()}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&exited, nil).Once()
+
+	var result *container.RunResult
+	runner := NewRunner(client, RunnerConfig{Timeout: time.Hour}, func(r *container.RunResult) { result = r })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	runner.Wait()
+
+	assert.False(t, result.TimedOut)
+	assert.Equal(t, container.StatusPassed, result.Status())
+	client.AssertExpectations(t)
+}
+
+// newRetryClient returns mock client, where test container runs exit with exitCodes
+func newRetryClient(c container.Container, exitCodes ...int) *mockclient.MockClient {
+	client := mockclient.NewMockClient()
+	for i, exitCode := range exitCodes {
+		id := fmt.Sprintf("attempt%d", i+1)
+		exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{ExitCode: exitCode, FinishedAt: time.Now()}}, nil)
+		client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*container.RunResult).ContainerID = id
+			}).Return(nil).Once()
+		client.On("WaitContainer", id).Return(&exited, nil).Once()
+	}
+
+	return client
+}
+
+func TestRunnerStartContainerFrom_RetryPassed(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotRetryCount: "3", container.TugbotRetryBackoff: "1ms"})
+	client := newRetryClient(c, 1, 1, 0)
+
+	var results []container.RunResult
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) { results = append(results, *r) })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	runner.Wait()
+
+	assert.Len(t, results, 3)
+	for i, r := range results {
+		assert.Equal(t, i+1, r.Attempt)
+		assert.Equal(t, i == 2, r.Final)
+	}
+	assert.Equal(t, results[0].ID, results[2].RetryOf)
+	assert.Equal(t, container.StatusFailed, results[1].Status())
+	assert.Equal(t, container.StatusPassedAfterRetry, results[2].Status())
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_RetryExceeded(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotRetryCount: "1", container.TugbotRetryBackoff: "1ms"})
+	client := newRetryClient(c, 1, 2)
+
+	var results []container.RunResult
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) { results = append(results, *r) })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	runner.Wait()
+
+	assert.Len(t, results, 2)
+	assert.False(t, results[0].Final)
+	assert.True(t, results[1].Final)
+	assert.Equal(t, container.StatusFailed, results[1].Status())
+	assert.Equal(t, 2, results[1].ExitCode)
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStartContainerFrom_RetryKeepsRunLock(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotRetryCount: "1", container.TugbotRetryBackoff: "50ms"})
+	client := newRetryClient(c, 1, 0)
+
+	var finished sync.WaitGroup
+	finished.Add(1)
+	runner := NewRunner(client, RunnerConfig{}, func(r *container.RunResult) {
+		if r.Attempt == 1 {
+			finished.Done()
+		}
+	})
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	finished.Wait()
+	// skipped: first attempt failed, retry is pending
+	assert.Equal(t, ErrSkipped, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	runner.Wait()
+
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "StartContainerFrom", 2)
+}
+
+func TestRunnerStartContainerFrom_RetryCanceledByReplace(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotRetryCount: "1", container.TugbotRetryBackoff: "50ms",
+		container.TugbotConcurrency: container.ConcurrencyReplace})
+	client := newRetryClient(c, 1, 0)
+	events := &actionRecorder{}
+
+	var finished sync.WaitGroup
+	finished.Add(1)
+	runner := NewRunner(client, RunnerConfig{Events: events.record}, func(r *container.RunResult) {
+		if r.Attempt == 1 && r.Trigger == container.TriggerTimer {
+			finished.Done()
+		}
+	})
+	first := container.NewRunResult(container.TriggerTimer, nil)
+	assert.NoError(t, runner.StartContainerFrom(c, first))
+	finished.Wait()
+	// replaces the pending retry of the first run
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerDocker, nil)))
+	runner.Wait()
+
+	assert.True(t, first.Final)
+	assert.Equal(t, 2, events.count(container.EventTestFinished+":"+container.StatusFailed))
+	assert.Equal(t, 1, events.count(container.EventTestFinished+":"+container.StatusPassed))
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStop_Retry(t *testing.T) {
+	c := newTestCandidate("candidate", map[string]string{container.TugbotRetryCount: "1", container.TugbotRetryBackoff: "1h"})
+	client := newRetryClient(c, 1)
+	events := &actionRecorder{}
+
+	var finished sync.WaitGroup
+	finished.Add(1)
+	runner := NewRunner(client, RunnerConfig{Events: events.record}, func(*container.RunResult) { finished.Done() })
+	assert.NoError(t, runner.StartContainerFrom(c, container.NewRunResult(container.TriggerTimer, nil)))
+	finished.Wait()
+	runner.Stop()
+	runner.Wait()
+
+	runs := runner.Runs()
+	assert.Len(t, runs, 1)
+	assert.True(t, runs[0].Final)
+	assert.Equal(t, 2, events.count(container.EventTestFinished+":"+container.StatusFailed))
+	assert.Empty(t, runner.active)
+	client.AssertExpectations(t)
+}
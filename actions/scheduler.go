@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/cron"
+)
+
+// cronScheduler runs recurring tasks on cron schedules.
+type cronScheduler struct {
+	mu    sync.Mutex
+	tasks map[string]chan struct{}
+}
+
+func newCronScheduler() *cronScheduler {
+	return &cronScheduler{tasks: make(map[string]chan struct{})}
+}
+
+// run starts a new task calling job on schedule s, returns false if task id is already running.
+func (cs *cronScheduler) run(id string, s *cron.Schedule, job func()) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.tasks[id]; ok {
+		return false
+	}
+	stop := make(chan struct{})
+	cs.tasks[id] = stop
+	go func() {
+		for {
+			next := s.Next(time.Now())
+			if next.IsZero() {
+				log.Errorf("Cron schedule %s has no next activation time, stopping task %s", s, id)
+				return
+			}
+			timer := time.NewTimer(next.Sub(time.Now()))
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+				job()
+			}
+		}
+	}()
+
+	return true
+}
+
+// refresh stops all tasks, that are not in ids.
+func (cs *cronScheduler) refresh(ids []string) {
+	keep := make(map[string]bool)
+	for _, id := range ids {
+		keep[id] = true
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for id, stop := range cs.tasks {
+		if !keep[id] {
+			close(stop)
+			delete(cs.tasks, id)
+			log.Infof("Cron task stopped (ID: %s)", id)
+		}
+	}
+}
+
+// stopAll stops all tasks.
+func (cs *cronScheduler) stopAll() {
+	cs.refresh(nil)
+}
@@ -0,0 +1,31 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/cron"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronScheduler(t *testing.T) {
+	s, err := cron.Parse("* * * * * *")
+	assert.NoError(t, err)
+	fired := make(chan string, 10)
+	scheduler := newCronScheduler()
+
+	assert.True(t, scheduler.run("task1", s, func() { fired <- "task1" }))
+	assert.False(t, scheduler.run("task1", s, func() { fired <- "duplicate" }))
+	select {
+	case id := <-fired:
+		assert.Equal(t, "task1", id)
+	case <-time.After(time.Second * 3):
+		assert.Fail(t, "cron task was not fired")
+	}
+
+	scheduler.refresh([]string{})
+	assert.Empty(t, scheduler.tasks)
+	assert.True(t, scheduler.run("task1", s, func() {}))
+	scheduler.stopAll()
+	assert.Empty(t, scheduler.tasks)
+}
@@ -9,16 +9,20 @@ import (
 	"time"
 )
 
-// RunTickerTestContainers on a clock intervals runs test containers that should run recurring.
-func RunTickerTestContainers(ctx context.Context, client container.Client, interval time.Duration) {
+// RunTickerTestContainers on a clock intervals runs test containers that should run recurring,
+// either on a fixed interval or on a cron schedule. Test containers subscribed to startup or
+// discovery are run once, on the first clock interval or when a new candidate is found.
+func RunTickerTestContainers(ctx context.Context, runner *Runner, interval time.Duration) {
 	manager := common.NewTaskManager()
+	scheduler := newCronScheduler()
 	ticker := time.NewTicker(interval)
 	for {
-		runNewTasks(manager, client)
+		runNewTasks(manager, scheduler, runner)
 		select {
 		case <-ctx.Done():
 			ticker.Stop()
 			manager.StopAllTasks()
+			scheduler.stopAll()
 			log.Info("Test Containers' Ticker Stopped.")
 
 			return
@@ -28,40 +32,76 @@ func RunTickerTestContainers(ctx context.Context, client container.Client, inter
 	}
 }
 
-func runNewTasks(manager common.TaskManager, client container.Client) {
-	candidates, err := client.ListContainers(func(c container.Container) bool {
+func runNewTasks(manager common.TaskManager, scheduler *cronScheduler, runner *Runner) {
+	candidates, err := runner.client.ListContainers(func(c container.Container) bool {
 		return c.IsTugbotCandidate()
 	})
 	if err != nil {
 		log.Errorf("Failed to get list test containers candidates for timer event (%v)", err)
 	} else {
-		var tasks []string
+		triggers := runner.discovery.discover(candidates)
+		var tasks, cronTasks, ids []string
 		for _, currCandidate := range candidates {
-			interval, ok := currCandidate.GetEventListenerInterval()
-			if ok {
-				currTaskId := currCandidate.ID()
+			ids = append(ids, currCandidate.ID())
+			spec := currCandidate.Spec()
+			if trigger, ok := triggers[currCandidate.ID()]; ok {
+				log.Infof("Ticker starting %s run... (Container ID: %s, Name: %s)", trigger, currCandidate.ID(), currCandidate.Name())
+				if err := startContainerFrom([]interface{}{runner, currCandidate, trigger}); err != nil {
+					log.Errorf("Failed to start %s run (Container ID: %s, Name: %s) (%v)", trigger, currCandidate.ID(), currCandidate.Name(), err)
+				}
+			}
+			if spec.Timer != nil {
+				currTaskId := taskID(currCandidate, spec.Timer.String())
 				currTask := common.Task{
 					ID:        currTaskId,
 					Name:      currCandidate.Name(),
 					Job:       startContainerFrom,
-					JobParams: []interface{}{client, currCandidate},
-					Interval:  interval}
+					JobParams: []interface{}{runner, currCandidate, container.TriggerTimer},
+					Interval:  *spec.Timer}
 				tasks = append(tasks, currTaskId)
 				if ok := manager.RunNewRecurringTask(currTask); ok {
-					log.Infof("Ticker starting new recuring task... (Container ID: %s, Name: %s, Interval: %s)", currTaskId, currTask.Name, currTask.Interval)
+					log.Infof("Ticker starting new recuring task... (Container ID: %s, Name: %s, Interval: %s)", currCandidate.ID(), currTask.Name, currTask.Interval)
+				}
+			}
+			if schedule := spec.Cron; schedule != nil {
+				currTaskId, currTaskName := taskID(currCandidate, schedule.String()), currCandidate.Name()
+				params := []interface{}{runner, currCandidate, container.TriggerCron}
+				cronTasks = append(cronTasks, currTaskId)
+				if ok := scheduler.run(currTaskId, schedule, func() {
+					if err := startContainerFrom(params); err != nil {
+						log.Errorf("Failed to start cron task (ID: %s, Name: %s) (%v)", currTaskId, currTaskName, err)
+					}
+				}); ok {
+					log.Infof("Ticker starting new cron task... (Container ID: %s, Name: %s, Cron: %s)", currCandidate.ID(), currTaskName, schedule)
 				}
 			}
 		}
 		manager.Refresh(tasks)
+		scheduler.refresh(cronTasks)
+		container.PruneSpecs(ids)
 	}
 }
 
+// taskID returns recurring task ID of test container c running on schedule, a task
+// is restarted when its schedule changes (e.g. on configuration file reload).
+func taskID(c container.Container, schedule string) string {
+	return c.ID() + "/" + schedule
+}
+
+// startContainerFrom starts a new test container from the current state of candidate,
+// so labels and configuration file definitions changed since task start are applied.
 func startContainerFrom(params []interface{}) error {
-	client := params[0].(container.Client)
+	runner := params[0].(*Runner)
 	c := params[1].(container.Container)
-	if _, err := client.Inspect(c.ID()); err != nil {
+	trigger := params[2].(string)
+	current, err := runner.client.Inspect(c.ID())
+	if err != nil {
+		return err
+	}
+
+	if err := runner.StartContainerFrom(*current, container.NewRunResult(trigger, nil)); failedToStart(err) {
 		return err
 	}
 
-	return client.StartContainerFrom(c)
+	return nil
 }
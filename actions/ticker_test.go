@@ -2,6 +2,7 @@ package actions
 
 import (
 	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot-common"
 	"github.com/gaia-docker/tugbot/container"
 	"github.com/gaia-docker/tugbot/container/mockclient"
 	"github.com/samalba/dockerclient"
@@ -23,6 +24,7 @@ func TestRunTickerTestContainers_FailedToGetListContainers(t *testing.T) {
 	wg2.Add(1)
 	ctx, cancel := context.WithCancel(context.Background())
 	client := mockclient.NewMockClient()
+	runner := NewRunner(client, RunnerConfig{})
 	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
 		Run(func(args mock.Arguments) {
 			wg1.Done()
@@ -30,7 +32,7 @@ func TestRunTickerTestContainers_FailedToGetListContainers(t *testing.T) {
 			cancel()
 		}).Return([]container.Container{}, errors.New("Expected :)")).Once()
 	go func() {
-		RunTickerTestContainers(ctx, client, time.Second*10)
+		RunTickerTestContainers(ctx, runner, time.Second*10)
 		wg2.Done()
 	}()
 	wg1.Wait()
@@ -59,6 +61,7 @@ func TestRunTickerTestContainers(t *testing.T) {
 		nil,
 	)
 	client := mockclient.NewMockClient()
+	runner := NewRunner(client, RunnerConfig{})
 
 	// Iteration 1
 	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
@@ -70,11 +73,12 @@ func TestRunTickerTestContainers(t *testing.T) {
 		Run(func(args mock.Arguments) {
 			assert.Equal(t, c.ID(), args.Get(0).(string))
 		}).Return(&c, nil).Once()
-	client.On("StartContainerFrom", mock.AnythingOfType("container.Container")).
+	client.On("StartContainerFrom", mock.AnythingOfType("container.Container"), mock.AnythingOfType("*container.RunResult")).
 		Run(func(args mock.Arguments) {
 			assert.Equal(t, c.Name(), args.Get(0).(container.Container).Name())
 			wg1.Done()
 		}).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&c, nil).Once()
 
 	// Iteration 2 - quit ticker
 	ctx, cancel := context.WithCancel(context.Background())
@@ -86,11 +90,12 @@ func TestRunTickerTestContainers(t *testing.T) {
 		Return([]container.Container{c}, nil).Once()
 
 	go func() {
-		RunTickerTestContainers(ctx, client, time.Nanosecond*1)
+		RunTickerTestContainers(ctx, runner, time.Nanosecond*1)
 		wg2.Done()
 	}()
 	wg1.Wait()
 	wg2.Wait()
+	runner.Wait()
 
 	client.AssertExpectations(t)
 }
@@ -125,6 +130,7 @@ func TestRunTickerTestContainers_Iteration2ContainsDifferentListOfContainers(t *
 	)
 
 	client := mockclient.NewMockClient()
+	runner := NewRunner(client, RunnerConfig{})
 
 	// Iteration 1 - c1
 	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
@@ -136,12 +142,13 @@ func TestRunTickerTestContainers_Iteration2ContainsDifferentListOfContainers(t *
 		Run(func(args mock.Arguments) {
 			assert.Equal(t, c1.ID(), args.Get(0).(string))
 		}).Return(&c1, nil).Once()
-	client.On("StartContainerFrom", mock.AnythingOfType("container.Container")).
+	client.On("StartContainerFrom", mock.AnythingOfType("container.Container"), mock.AnythingOfType("*container.RunResult")).
 		Run(func(args mock.Arguments) {
 			name := args.Get(0).(container.Container).Name()
 			log.Info("Running container ", name)
 			assert.Equal(t, c1.Name(), name)
 		}).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&c1, nil).Once()
 
 	// Iteration 2 - c2
 	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
@@ -153,13 +160,14 @@ func TestRunTickerTestContainers_Iteration2ContainsDifferentListOfContainers(t *
 		Run(func(args mock.Arguments) {
 			assert.Equal(t, c2.ID(), args.Get(0).(string))
 		}).Return(&c2, nil).Once()
-	client.On("StartContainerFrom", mock.AnythingOfType("container.Container")).
+	client.On("StartContainerFrom", mock.AnythingOfType("container.Container"), mock.AnythingOfType("*container.RunResult")).
 		Run(func(args mock.Arguments) {
 			name := args.Get(0).(container.Container).Name()
 			log.Info("Running container ", name)
 			assert.Equal(t, c2.Name(), name)
 			wg1.Done()
 		}).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&c2, nil).Once()
 
 	// Iteration 3 - no containers - quit ticker
 	ctx, cancel := context.WithCancel(context.Background())
@@ -172,13 +180,14 @@ func TestRunTickerTestContainers_Iteration2ContainsDifferentListOfContainers(t *
 		Return([]container.Container{}, nil).Once()
 
 	go func() {
-		RunTickerTestContainers(ctx, client, time.Nanosecond*1)
+		RunTickerTestContainers(ctx, runner, time.Nanosecond*1)
 		wg2.Done()
 	}()
 	log.Info("Wating for finish running container ", c2.Name())
 	wg1.Wait()
 	log.Info("Wating for quiting ticker")
 	wg2.Wait()
+	runner.Wait()
 
 	client.AssertExpectations(t)
 }
@@ -204,6 +213,7 @@ func TestRunTickerTestContainers_FailedToFindBasicContainer(t *testing.T) {
 	)
 
 	client := mockclient.NewMockClient()
+	runner := NewRunner(client, RunnerConfig{})
 
 	// Iteration 1
 	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).
@@ -228,7 +238,7 @@ func TestRunTickerTestContainers_FailedToFindBasicContainer(t *testing.T) {
 		Return([]container.Container{}, nil).Once()
 
 	go func() {
-		RunTickerTestContainers(ctx, client, time.Nanosecond*1)
+		RunTickerTestContainers(ctx, runner, time.Nanosecond*1)
 		wg2.Done()
 	}()
 	log.Info("Wating for inpect of ", c.Name())
@@ -238,3 +248,66 @@ func TestRunTickerTestContainers_FailedToFindBasicContainer(t *testing.T) {
 
 	client.AssertExpectations(t)
 }
+
+// recordingTaskManager records recurring tasks without running them
+type recordingTaskManager struct {
+	started []string
+	running []string
+}
+
+func (m *recordingTaskManager) RunNewRecurringTask(task common.Task) bool {
+	for _, id := range m.running {
+		if id == task.ID {
+			return false
+		}
+	}
+	m.started = append(m.started, task.ID)
+	m.running = append(m.running, task.ID)
+	return true
+}
+
+func (m *recordingTaskManager) Refresh(ids []string) {
+	m.running = ids
+}
+
+func (m *recordingTaskManager) StopAllTasks() {
+	m.running = nil
+}
+
+func TestRunNewTasks_ScheduleChanged(t *testing.T) {
+	before := newTestCandidate("cid", map[string]string{container.TugbotEventTimer: "1h", container.TugbotEventCron: "0 0 1 1 *"})
+	after := newTestCandidate("cid", map[string]string{container.TugbotEventTimer: "2h", container.TugbotEventCron: "0 0 2 1 *"})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).Return([]container.Container{before}, nil).Once()
+	client.On("ListContainers", mock.AnythingOfType(containerFilterType)).Return([]container.Container{after}, nil).Once()
+	manager := &recordingTaskManager{}
+	scheduler := newCronScheduler()
+	defer scheduler.stopAll()
+	runner := NewRunner(client, RunnerConfig{})
+
+	runNewTasks(manager, scheduler, runner)
+	// definitions changed, e.g. on configuration file reload
+	runNewTasks(manager, scheduler, runner)
+
+	assert.Equal(t, []string{"cid/1h0m0s", "cid/2h0m0s"}, manager.started)
+	assert.Equal(t, []string{"cid/2h0m0s"}, manager.running)
+	assert.Len(t, scheduler.tasks, 1)
+	_, ok := scheduler.tasks["cid/0 0 2 1 *"]
+	assert.True(t, ok)
+	client.AssertExpectations(t)
+}
+
+func TestStartContainerFrom_Inspected(t *testing.T) {
+	stale := newTestCandidate("cid", map[string]string{container.TugbotEventTimer: "1h", container.TugbotEventCron: "0 0 1 1 *"})
+	current := newTestCandidate("cid", map[string]string{container.TugbotEventTimer: "2h", container.TugbotEventCron: "0 0 2 1 *"})
+	client := mockclient.NewMockClient()
+	client.On("Inspect", stale.ID()).Return(&current, nil).Once()
+	client.On("StartContainerFrom", current, mock.AnythingOfType("*container.RunResult")).Return(nil).Once()
+	client.On("WaitContainer", mock.AnythingOfType("string")).Return(&current, nil).Once()
+	runner := NewRunner(client, RunnerConfig{})
+
+	assert.NoError(t, startContainerFrom([]interface{}{runner, stale, container.TriggerTimer}))
+	runner.Wait()
+
+	client.AssertExpectations(t)
+}
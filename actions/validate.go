@@ -0,0 +1,27 @@
+package actions
+
+import (
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// Validation is a test container with its misconfiguration, no errors if test container is configured properly.
+type Validation struct {
+	Container container.Container
+	Errors    []error
+}
+
+// Validate looks at test containers (running or not), all or by names, and validates their labels.
+func Validate(client container.Client, names []string) ([]Validation, error) {
+	tests, err := client.ListContainers(func(c container.Container) bool {
+		return nameFilter(names)(c) && c.IsTugbotTest()
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Validation, 0, len(tests))
+	for _, test := range tests {
+		ret = append(ret, Validation{Container: test, Errors: test.Validate()})
+	}
+
+	return ret, nil
+}
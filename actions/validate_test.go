@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestValidate(t *testing.T) {
+	valid := *container.NewContainer(&dockerclient.ContainerInfo{
+		Name: "/valid",
+		Config: &dockerclient.ContainerConfig{Labels: map[string]string{
+			container.TugbotTest:        "true",
+			container.TugbotEventDocker: "",
+			container.ActionFilter:      "start",
+		}},
+		State: stateExited,
+	}, nil)
+	misconfigured := *container.NewContainer(&dockerclient.ContainerInfo{
+		Name: "/misconfigured",
+		Config: &dockerclient.ContainerConfig{Labels: map[string]string{
+			container.TugbotTest:                "true",
+			container.TugbotEventDocker:         "",
+			"tugbot-event-docker-filter-actoin": "start",
+		}},
+		State: &dockerclient.State{Running: true},
+	}, nil)
+	created := *container.NewContainer(&dockerclient.ContainerInfo{
+		Name: "/created",
+		Config: &dockerclient.ContainerConfig{Labels: map[string]string{
+			container.TugbotTest:        "true",
+			container.TugbotCreatedFrom: "valid",
+		}},
+		State: stateExited,
+	}, nil)
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Run(func(args mock.Arguments) {
+		filter := args.Get(0).(container.Filter)
+		assert.True(t, filter(valid))
+		assert.True(t, filter(misconfigured))
+		assert.False(t, filter(created))
+	}).Return([]container.Container{valid, misconfigured}, nil).Once()
+
+	validations, err := Validate(client, nil)
+
+	assert.NoError(t, err)
+	if assert.Len(t, validations, 2) {
+		assert.Equal(t, "valid", validations[0].Container.Name())
+		assert.Empty(t, validations[0].Errors)
+		assert.Equal(t, "misconfigured", validations[1].Container.Name())
+		assert.Len(t, validations[1].Errors, 1)
+	}
+	client.AssertExpectations(t)
+}
+
+func TestValidate_Error(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, errors.New("no docker")).Once()
+
+	_, err := Validate(client, nil)
+
+	assert.EqualError(t, err, "no docker")
+	client.AssertExpectations(t)
+}
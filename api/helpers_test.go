@@ -0,0 +1,26 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// newTestCandidate returns a running test container candidate with name and labels.
+func newTestCandidate(name string, labels map[string]string) container.Container {
+	l := map[string]string{container.TugbotTest: "true"}
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	return *container.NewContainer(
+		&dockerclient.ContainerInfo{
+			Id:     name + "-id",
+			Name:   "/" + name,
+			Config: &dockerclient.ContainerConfig{Image: "tests:latest", Labels: l},
+			State:  &dockerclient.State{StartedAt: time.Now()},
+		},
+		nil,
+	)
+}
@@ -0,0 +1,257 @@
+// Package api implements tugbot HTTP API: list test containers, trigger test
+// container runs on demand and list recent runs.
+//
+//	GET  /containers             - list test containers with their trigger configuration
+//	POST /containers/{name}/run  - run test container
+//	GET  /runs                   - list recent test container runs
+//	POST /webhook                - run test containers matching inbound webhook event (token required)
+//	GET  /deliveries             - list webhooks delivery metrics
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/actions"
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/webhooks"
+)
+
+// Candidate is a test container discovered by tugbot with its parsed configuration.
+type Candidate struct {
+	ID            string
+	Name          string
+	ImageName     string
+	Triggers      Triggers
+	Concurrency   string
+	Priority      int
+	ResultsDir    string
+	ResultsFormat string   `json:",omitempty"`
+	Errors        []string `json:",omitempty"`
+}
+
+// Triggers is a test container trigger configuration.
+type Triggers struct {
+	Docker         bool              `json:",omitempty"`
+	DockerFilters  map[string]string `json:",omitempty"`
+	Debounce       string            `json:",omitempty"`
+	Webhook        bool              `json:",omitempty"`
+	WebhookFilters map[string]string `json:",omitempty"`
+	Timer          string            `json:",omitempty"`
+	Cron           string            `json:",omitempty"`
+	Startup        bool              `json:",omitempty"`
+	Discovered     bool              `json:",omitempty"`
+}
+
+// Run is a test container run with its status.
+type Run struct {
+	container.RunResult
+	Status string
+}
+
+// Triggered is the response of a test container run request.
+type Triggered struct {
+	// RunID is the ID of started (or queued) run, empty if the run is not started
+	RunID string `json:",omitempty"`
+	Name  string
+	// Reason is why the run is not started, for example skipped by concurrency policy
+	Reason string `json:",omitempty"`
+}
+
+// Server is tugbot HTTP API server.
+type Server struct {
+	client       container.Client
+	runner       *actions.Runner
+	publisher    *webhooks.Publisher
+	webhookToken string
+	mux          *http.ServeMux
+	listener     net.Listener
+}
+
+// NewServer returns a new API Server, that runs test containers using runner and reports
+// delivery metrics of publisher (nil if events are not published to webhooks).
+// Requests triggering test container runs must be authenticated with webhookToken, empty token
+// disables webhook trigger (API run requests are not authenticated then).
+func NewServer(client container.Client, runner *actions.Runner, publisher *webhooks.Publisher, webhookToken string) *Server {
+	s := &Server{client: client, runner: runner, publisher: publisher, webhookToken: webhookToken, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/containers", s.listContainers)
+	s.mux.HandleFunc("/containers/", s.runContainer)
+	s.mux.HandleFunc("/runs", s.listRuns)
+	s.mux.HandleFunc("/webhook", s.webhook)
+	s.mux.HandleFunc("/deliveries", s.listDeliveries)
+
+	return s
+}
+
+// Start starts listening on addr and serving API requests in background.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go func() {
+		if err := http.Serve(listener, s); err != nil {
+			log.Debugf("API server stopped (%v)", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops listening for API requests.
+func (s *Server) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("API request: %s %s", r.Method, r.URL.Path)
+	if s.webhookToken != "" && r.Method != http.MethodGet && !s.authorized(r) {
+		log.Warnf("Unauthorized API request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) listContainers(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	candidates, err := s.client.ListContainers(func(c container.Container) bool {
+		return c.IsTugbotCandidate()
+	})
+	if err != nil {
+		log.Errorf("Failed to list test containers (%v)", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ret := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		ret = append(ret, newCandidate(c))
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) runContainer(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/containers/")
+	if !strings.HasSuffix(name, "/run") {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimSuffix(name, "/run")
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	candidates, err := s.client.ListContainers(func(c container.Container) bool {
+		return c.IsTugbotCandidate() && c.Name() == name
+	})
+	if err != nil {
+		log.Errorf("Failed to list test containers (%v)", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(candidates) == 0 {
+		http.Error(w, fmt.Sprintf("Test container not found: %s", name), http.StatusNotFound)
+		return
+	}
+	run := container.NewRunResult(container.TriggerAPI, nil)
+	log.Infof("Starting %s requested via API (Run ID: %s)", name, run.ID)
+	err = s.runner.StartContainerFrom(candidates[0], run)
+	if err == actions.ErrDryRun {
+		writeJSON(w, http.StatusOK, Triggered{Name: name, Reason: err.Error()})
+		return
+	} else if err == actions.ErrSkipped {
+		http.Error(w, fmt.Sprintf("Test container %s is already running, run skipped by %s concurrency policy", name, container.ConcurrencySkip), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to start %s (%v)", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, Triggered{RunID: run.ID, Name: name})
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	runs := s.runner.Runs()
+	ret := make([]Run, 0, len(runs))
+	for _, run := range runs {
+		ret = append(ret, Run{RunResult: run, Status: run.Status()})
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	ret := []webhooks.Stats{}
+	if s.publisher != nil {
+		ret = s.publisher.Stats()
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func newCandidate(c container.Container) Candidate {
+	ret := Candidate{
+		ID:            c.ID(),
+		Name:          c.Name(),
+		ImageName:     c.ImageName(),
+		Concurrency:   c.GetConcurrencyPolicy(),
+		Priority:      c.GetPriority(),
+		ResultsDir:    c.ResultsDir(),
+		ResultsFormat: c.ResultsFormat(),
+	}
+	if filters, ok := c.GetEventDockerFilters(); ok {
+		ret.Triggers.Docker = true
+		ret.Triggers.DockerFilters = filters
+		if debounce, ok := c.GetEventDebounce(); ok {
+			ret.Triggers.Debounce = debounce.String()
+		}
+	}
+	if filters, ok := c.GetEventWebhookFilters(); ok {
+		ret.Triggers.Webhook = true
+		ret.Triggers.WebhookFilters = filters
+	}
+	if interval, ok := c.GetEventListenerInterval(); ok {
+		ret.Triggers.Timer = interval.String()
+	}
+	if schedule, ok := c.GetEventCron(); ok {
+		ret.Triggers.Cron = schedule.String()
+	}
+	ret.Triggers.Startup = c.IsStartupListener()
+	ret.Triggers.Discovered = c.IsDiscoveryListener()
+	for _, err := range c.Spec().Errors {
+		ret.Errors = append(ret.Errors, err.Error())
+	}
+
+	return ret
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, fmt.Sprintf("Method not allowed: %s", r.Method), http.StatusMethodNotAllowed)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to write API response (%v)", err)
+	}
+}
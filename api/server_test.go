@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/actions"
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/gaia-docker/tugbot/webhooks"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func serve(s *Server, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+	return w
+}
+
+func TestListContainers(t *testing.T) {
+	c := newTestCandidate("api-tests", map[string]string{
+		container.TugbotEventDocker: "",
+		container.TypeFilter:        "container",
+		container.TugbotEventCron:   "0 2 * * *",
+		container.TugbotPriority:    "3",
+	})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil).Once()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodGet, "/containers")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var candidates []Candidate
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&candidates))
+	assert.Equal(t, []Candidate{{
+		ID:          "api-tests-id",
+		Name:        "api-tests",
+		ImageName:   "tests:latest",
+		Concurrency: container.ConcurrencySkip,
+		Priority:    3,
+		ResultsDir:  container.DefaultResultsDir,
+		Triggers: Triggers{
+			Docker:        true,
+			DockerFilters: map[string]string{"type": "container"},
+			Cron:          "0 2 * * *",
+		},
+	}}, candidates)
+	client.AssertExpectations(t)
+}
+
+func TestListContainers_Misconfigured(t *testing.T) {
+	c := newTestCandidate("misconfigured-tests", map[string]string{
+		container.TugbotEventTimer: "often",
+	})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil).Once()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodGet, "/containers")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var candidates []Candidate
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&candidates))
+	if assert.Len(t, candidates, 1) {
+		assert.Empty(t, candidates[0].Triggers.Timer)
+		if assert.Len(t, candidates[0].Errors, 1) {
+			assert.Contains(t, candidates[0].Errors[0], "invalid tugbot-event-timer label: often")
+		}
+	}
+	client.AssertExpectations(t)
+}
+
+func TestListContainers_Error(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, errors.New("whoops")).Once()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodGet, "/containers")
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer(t *testing.T) {
+	c := newTestCandidate("api-tests", map[string]string{})
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{ExitCode: 1, FinishedAt: time.Now()}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).
+		Run(func(args mock.Arguments) {
+			filter := args.Get(0).(container.Filter)
+			assert.True(t, filter(c))
+			assert.False(t, filter(newTestCandidate("other", map[string]string{})))
+		}).Return([]container.Container{c}, nil).Once()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			run := args.Get(1).(*container.RunResult)
+			assert.Equal(t, container.TriggerAPI, run.Trigger)
+			run.ContainerID = "created"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "created").Return(&exited, nil).Once()
+	runner := actions.NewRunner(client, actions.RunnerConfig{})
+	s := NewServer(client, runner, nil, "")
+
+	w := serve(s, http.MethodPost, "/containers/api-tests/run")
+	runner.Wait()
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var triggered Triggered
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&triggered))
+	assert.Equal(t, "api-tests", triggered.Name)
+	assert.NotEmpty(t, triggered.RunID)
+
+	w = serve(s, http.MethodGet, "/runs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var runs []Run
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&runs))
+	assert.Len(t, runs, 1)
+	assert.Equal(t, triggered.RunID, runs[0].ID)
+	assert.Equal(t, container.StatusFailed, runs[0].Status)
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer_Unauthorized(t *testing.T) {
+	client := mockclient.NewMockClient()
+	s := NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, "/containers/x/run").Code)
+	client.AssertNotCalled(t, "ListContainers", mock.Anything)
+	client.AssertNotCalled(t, "StartContainerFrom", mock.Anything, mock.Anything)
+}
+
+func TestRunContainer_Authorized(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil).Once()
+	s := NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/containers/x/run", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+
+	s.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer_Skipped(t *testing.T) {
+	c := newTestCandidate("api-tests", map[string]string{})
+	release := make(chan struct{})
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{FinishedAt: time.Now()}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil).Twice()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*container.RunResult).ContainerID = "created"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "created").Run(func(mock.Arguments) { <-release }).Return(&exited, nil).Once()
+	runner := actions.NewRunner(client, actions.RunnerConfig{})
+	s := NewServer(client, runner, nil, "")
+
+	assert.Equal(t, http.StatusAccepted, serve(s, http.MethodPost, "/containers/api-tests/run").Code)
+	w := serve(s, http.MethodPost, "/containers/api-tests/run")
+	close(release)
+	runner.Wait()
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Len(t, runner.Runs(), 1)
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer_DryRun(t *testing.T) {
+	c := newTestCandidate("api-tests", map[string]string{})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil).Once()
+	runner := actions.NewRunner(client, actions.RunnerConfig{DryRun: true})
+
+	w := serve(NewServer(client, runner, nil, ""), http.MethodPost, "/containers/api-tests/run")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var triggered Triggered
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&triggered))
+	assert.Equal(t, Triggered{Name: "api-tests", Reason: "dry run, not started"}, triggered)
+	assert.Empty(t, runner.Runs())
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer_NotFound(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil).Once()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodPost, "/containers/missing/run")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	client.AssertExpectations(t)
+}
+
+func TestRunContainer_MethodNotAllowed(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodGet, "/containers/api-tests/run")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+}
+
+func TestRunContainer_UnknownPath(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodPost, "/containers/api-tests/stop")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestListDeliveries(t *testing.T) {
+	p, err := webhooks.NewPublisher(webhooks.NewEndpoints([]string{"http://result-service:8081/events"}), webhooks.Config{InitialBackoff: time.Hour})
+	assert.NoError(t, err)
+	p.Stop()
+	client := mockclient.NewMockClient()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), p, ""), http.MethodGet, "/deliveries")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats []webhooks.Stats
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "http://result-service:8081/events", stats[0].URL)
+}
+
+func TestListDeliveries_NoWebhooks(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	w := serve(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), http.MethodGet, "/deliveries")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/actions"
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// webhookTokenHeader is an alternative to 'Authorization: Bearer <token>' header
+const webhookTokenHeader = "X-Tugbot-Token"
+
+// maxWebhookPayload is the max size of inbound webhook request body
+const maxWebhookPayload = 1 << 20
+
+// webhook starts test containers subscribed to inbound webhook events, that match
+// the request JSON payload.
+func (s *Server) webhook(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if s.webhookToken == "" {
+		http.Error(w, "Webhook trigger is disabled, use --webhook-token to enable it", http.StatusForbidden)
+		return
+	}
+	// read one byte over the limit to detect larger payloads
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayload+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read payload (%v)", err), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxWebhookPayload {
+		http.Error(w, fmt.Sprintf("Payload exceeds %d bytes", maxWebhookPayload), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var body map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep numbers as sent, e.g. build number 12345678 instead of 1.2345678e+07
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON payload (%v)", err), http.StatusBadRequest)
+		return
+	}
+	payload := make(map[string]string)
+	flatten("", body, payload)
+	log.Debugf("Looking for test containers that should run on webhook event: %+v", payload)
+
+	candidates, err := s.client.ListContainers(func(c container.Container) bool {
+		return c.IsTugbotCandidate() && c.IsWebhookListener(payload)
+	})
+	if err != nil {
+		log.Errorf("Failed to list test containers (%v)", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ret := make([]Triggered, 0, len(candidates))
+	for _, c := range candidates {
+		run := container.NewRunResult(container.TriggerWebhook, nil)
+		run.Payload = payload
+		log.Infof("Starting %s triggered by webhook event (Run ID: %s)", c.Name(), run.ID)
+		if err := s.runner.StartContainerFrom(c, run); err != nil {
+			if err != actions.ErrSkipped && err != actions.ErrDryRun {
+				log.Errorf("Failed to start %s (%v)", c.Name(), err)
+			}
+			ret = append(ret, Triggered{Name: c.Name(), Reason: err.Error()})
+			continue
+		}
+		ret = append(ret, Triggered{RunID: run.ID, Name: c.Name()})
+	}
+	writeJSON(w, http.StatusAccepted, ret)
+}
+
+// authorized returns whether or not request r carries the token, requests triggering
+// test container runs (all but GET requests) are authorized by Server.ServeHTTP.
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.Header.Get(webhookTokenHeader)
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookToken)) == 1
+}
+
+// flatten flattens JSON object into field name to value map, nested object fields
+// are joined with '.'; arrays are ignored.
+func flatten(prefix string, v interface{}, out map[string]string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flatten(k, child, out)
+		}
+	case []interface{}, nil:
+	case string:
+		out[prefix] = val
+	case json.Number:
+		out[prefix] = val.String()
+	default:
+		out[prefix] = fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/actions"
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testToken = "s3cr3t"
+
+func postWebhook(s *Server, header string, token string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	if header != "" {
+		r.Header.Set(header, token)
+	}
+	s.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestWebhook(t *testing.T) {
+	c := newTestCandidate("voting-tests", map[string]string{
+		container.TugbotEventWebhook:                     "",
+		container.WebhookFilterPrefix + "service":        "voting-app",
+		container.WebhookFilterPrefix + "deploy.version": "re2:^1\\.",
+	})
+	other := newTestCandidate("worker-tests", map[string]string{
+		container.TugbotEventWebhook:              "",
+		container.WebhookFilterPrefix + "service": "worker",
+	})
+	exited := *container.NewContainer(&dockerclient.ContainerInfo{State: &dockerclient.State{FinishedAt: time.Now()}}, nil)
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).
+		Run(func(args mock.Arguments) {
+			filter := args.Get(0).(container.Filter)
+			assert.True(t, filter(c))
+			assert.False(t, filter(other))
+		}).Return([]container.Container{c}, nil).Once()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).
+		Run(func(args mock.Arguments) {
+			run := args.Get(1).(*container.RunResult)
+			assert.Equal(t, container.TriggerWebhook, run.Trigger)
+			assert.Equal(t, map[string]string{"service": "voting-app", "deploy.version": "1.3.0", "deploy.build": "12345678"}, run.Payload)
+			run.ContainerID = "created"
+		}).Return(nil).Once()
+	client.On("WaitContainer", "created").Return(&exited, nil).Once()
+	runner := actions.NewRunner(client, actions.RunnerConfig{})
+
+	w := postWebhook(NewServer(client, runner, nil, testToken), "Authorization", "Bearer "+testToken,
+		`{"service": "voting-app", "deploy": {"version": "1.3.0", "build": 12345678, "hosts": ["a", "b"]}}`)
+	runner.Wait()
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var triggered []Triggered
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&triggered))
+	assert.Len(t, triggered, 1)
+	assert.Equal(t, "voting-tests", triggered[0].Name)
+	client.AssertExpectations(t)
+}
+
+func TestWebhook_TokenHeader(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil).Once()
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken), webhookTokenHeader, testToken, `{}`)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	client.AssertExpectations(t)
+}
+
+func TestWebhook_Unauthorized(t *testing.T) {
+	client := mockclient.NewMockClient()
+	s := NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken)
+
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(s, "", "", `{}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(s, "Authorization", "Bearer wrong", `{}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(s, webhookTokenHeader, "", `{}`).Code)
+}
+
+func TestWebhook_Disabled(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, ""), webhookTokenHeader, "", `{}`)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWebhook_InvalidPayload(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken), webhookTokenHeader, testToken, `not json`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWebhook_PayloadTooLarge(t *testing.T) {
+	client := mockclient.NewMockClient()
+	body := `{"service": "` + strings.Repeat("x", maxWebhookPayload) + `"}`
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken), webhookTokenHeader, testToken, body)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	client.AssertNotCalled(t, "ListContainers", mock.Anything)
+}
+
+func TestWebhook_PayloadAtLimit(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{}, nil).Once()
+	body := `{"service": "` + strings.Repeat("x", maxWebhookPayload-len(`{"service": ""}`)) + `"}`
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken), webhookTokenHeader, testToken, body)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	client.AssertExpectations(t)
+}
+
+func TestWebhook_StartError(t *testing.T) {
+	c := newTestCandidate("voting-tests", map[string]string{container.TugbotEventWebhook: ""})
+	client := mockclient.NewMockClient()
+	client.On("ListContainers", mock.AnythingOfType("container.Filter")).Return([]container.Container{c}, nil).Once()
+	client.On("StartContainerFrom", c, mock.AnythingOfType("*container.RunResult")).Return(errors.New("no such image")).Once()
+
+	w := postWebhook(NewServer(client, actions.NewRunner(client, actions.RunnerConfig{}), nil, testToken), webhookTokenHeader, testToken, `{}`)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var triggered []Triggered
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&triggered))
+	assert.Equal(t, []Triggered{{Name: "voting-tests", Reason: "no such image"}}, triggered)
+	client.AssertExpectations(t)
+}
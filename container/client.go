@@ -3,6 +3,10 @@ package container
 import (
 	"crypto/tls"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -17,25 +21,37 @@ type Filter func(Container) bool
 // Docker API.
 type Client interface {
 	ListContainers(Filter) ([]Container, error)
-	StartContainerFrom(Container) error
+	StartContainerFrom(Container, *RunResult) error
+	WaitContainer(containerID string) (*Container, error)
+	CopyFromContainer(containerID, path string) (io.ReadCloser, error)
+	StopContainer(containerID string, timeout time.Duration) error
+	KillContainer(containerID string) error
+	RemoveContainer(containerID string) error
 	StartMonitorEvents(dockerclient.Callback)
 	StopAllMonitorEvents()
 	Inspect(containerID string) (*Container, error)
 }
 
 // NewClient returns a new Client instance which can be used to interact with
-// the Docker API.
-func NewClient(dockerHost string, tlsConfig *tls.Config, pullImages bool) Client {
+// the Docker API. Containers matching test definitions (may be nil) get labels
+// derived from them.
+func NewClient(dockerHost string, tlsConfig *tls.Config, pullImages bool, definitions *TestDefinitions) Client {
 	docker, err := dockerclient.NewDockerClient(dockerHost, tlsConfig)
 	if err != nil {
 		log.Fatalf("Error instantiating Docker client: %s", err)
 	}
 
-	return dockerClient{api: docker}
+	return dockerClient{api: docker, url: docker.URL.String(), httpClient: docker.HTTPClient, definitions: definitions}
 }
 
+// archiveAPIVersion is the first Docker API version supporting container archive endpoint
+const archiveAPIVersion = "v1.20"
+
 type dockerClient struct {
-	api dockerclient.Client
+	api         dockerclient.Client
+	url         string
+	httpClient  *http.Client
+	definitions *TestDefinitions
 }
 
 func (client dockerClient) ListContainers(fn Filter) ([]Container, error) {
@@ -59,27 +75,110 @@ func (client dockerClient) ListContainers(fn Filter) ([]Container, error) {
 	return ret, nil
 }
 
-func (client dockerClient) StartContainerFrom(c Container) error {
-	config := c.containerInfo.Config
+func (client dockerClient) StartContainerFrom(c Container, r *RunResult) error {
 	hostConfig := c.hostConfig()
 	name := c.Name()
-	if config.Labels == nil {
-		config.Labels = make(map[string]string)
-	}
-	config.Labels[TugbotCreatedFrom] = name
+	config := runConfig(c, r)
 
 	log.Debugf("Starting container from %s", name)
 	var err error
 	var newContainerID string
-	newContainerName := fmt.Sprintf("tugbot_%s_%s", name, time.Now().Format("20060102150405"))
+	// run ID keeps names unique, when a test container runs more than once a second
+	suffix := r.ID
+	if suffix == "" {
+		suffix = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	newContainerName := fmt.Sprintf("tugbot_%s_%s_%s", name, time.Now().Format("20060102150405"), suffix)
 	newContainerID, err = client.api.CreateContainer(config, newContainerName, nil)
 	if err != nil {
 		return err
 	}
 
 	log.Infof("Starting container %s (%s)", newContainerName, newContainerID)
+	if err = client.api.StartContainer(newContainerID, hostConfig); err != nil {
+		// created container is not tracked by the run, remove it
+		if rmErr := client.api.RemoveContainer(newContainerID, true, true); rmErr != nil {
+			log.Errorf("Failed to remove container %s (%s), that failed to start (%v)", newContainerName, newContainerID, rmErr)
+		}
+		return err
+	}
+	r.CreatedFrom = name
+	r.ContainerID = newContainerID
+	r.ContainerName = newContainerName
+	r.ImageName = c.ImageName()
+	r.StartedAt = time.Now()
+
+	return nil
+}
+
+// runConfig returns a copy of test container c config for run r: marked as created
+// by tugbot, with run overrides and run context environment variables.
+func runConfig(c Container, r *RunResult) *dockerclient.ContainerConfig {
+	config := *c.containerInfo.Config
+	config.Labels = make(map[string]string)
+	for k, v := range c.containerInfo.Config.Labels {
+		config.Labels[k] = v
+	}
+	config.Labels[TugbotCreatedFrom] = c.Name()
+	if cmd, ok := c.GetRunCmd(); ok {
+		config.Cmd = cmd
+	}
+	if entrypoint, ok := c.GetRunEntrypoint(); ok {
+		config.Entrypoint = entrypoint
+	}
+	if workdir, ok := c.GetRunWorkdir(); ok {
+		config.WorkingDir = workdir
+	}
+	runEnv, _ := c.GetRunEnv()
+	config.Env = mergeEnv(c.containerInfo.Config.Env, runEnv, r.Env())
+
+	return &config
+}
+
+// WaitContainer blocks until the container stops and returns its inspected state.
+func (client dockerClient) WaitContainer(containerID string) (*Container, error) {
+	res := <-client.api.Wait(containerID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return client.Inspect(containerID)
+}
+
+// CopyFromContainer returns a tar archive of the path content inside the container.
+func (client dockerClient) CopyFromContainer(containerID, path string) (io.ReadCloser, error) {
+	uri := fmt.Sprintf("%s/%s/containers/%s/archive?path=%s", client.url, archiveAPIVersion, containerID, url.QueryEscape(path))
+	resp, err := client.httpClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Failed to copy %s from container %s (%s)", path, containerID, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// StopContainer stops the container, killing it after timeout.
+func (client dockerClient) StopContainer(containerID string, timeout time.Duration) error {
+	log.Debugf("Stopping container %s", containerID)
+
+	return client.api.StopContainer(containerID, int(timeout.Seconds()))
+}
+
+// KillContainer kills the container.
+func (client dockerClient) KillContainer(containerID string) error {
+	log.Debugf("Killing container %s", containerID)
+
+	return client.api.KillContainer(containerID, "SIGKILL")
+}
+
+// RemoveContainer removes the container and its volumes.
+func (client dockerClient) RemoveContainer(containerID string) error {
+	log.Debugf("Removing container %s", containerID)
 
-	return client.api.StartContainer(newContainerID, hostConfig)
+	return client.api.RemoveContainer(containerID, false, true)
 }
 
 func (client dockerClient) StartMonitorEvents(cb dockerclient.Callback) {
@@ -103,5 +202,5 @@ func (client dockerClient) Inspect(containerID string) (*Container, error) {
 		return nil, err
 	}
 
-	return &Container{containerInfo: containerInfo, imageInfo: imageInfo}, nil
+	return &Container{containerInfo: client.definitions.apply(containerInfo), imageInfo: imageInfo}, nil
 }
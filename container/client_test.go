@@ -2,8 +2,12 @@ package container
 
 import (
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/samalba/dockerclient"
 	"github.com/samalba/dockerclient/mockclient"
@@ -53,6 +57,32 @@ func TestListContainers_Filter(t *testing.T) {
 	api.AssertExpectations(t)
 }
 
+func TestListContainers_TestDefinitions(t *testing.T) {
+	ci := &dockerclient.ContainerInfo{Name: "/api-tests", Image: "abc123", Config: &dockerclient.ContainerConfig{
+		Image:  "acme/api-tests:1.2",
+		Labels: map[string]string{TugbotTimeout: "1m"},
+	}}
+	api := mockclient.NewMockClient()
+	api.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "foo", Names: []string{"api-tests"}}}, nil)
+	api.On("InspectContainer", "foo").Return(ci, nil)
+	api.On("InspectImage", "abc123").Return(&dockerclient.ImageInfo{}, nil)
+	definitions := &TestDefinitions{}
+	definitions.Set([]TestDefinition{{Image: "re2:^acme/", Cron: "0 2 * * *", Timeout: "5m"}})
+
+	client := dockerClient{api: api, definitions: definitions}
+	cs, err := client.ListContainers(allContainers)
+
+	assert.NoError(t, err)
+	assert.Len(t, cs, 1)
+	schedule, ok := cs[0].GetEventCron()
+	assert.True(t, ok)
+	assert.Equal(t, "0 2 * * *", schedule.String())
+	timeout, _ := cs[0].GetTimeout()
+	assert.Equal(t, time.Minute, timeout)
+	assert.Equal(t, map[string]string{TugbotTimeout: "1m"}, ci.Config.Labels)
+	api.AssertExpectations(t)
+}
+
 func TestListContainers_ListError(t *testing.T) {
 	api := mockclient.NewMockClient()
 	api.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, errors.New("oops"))
@@ -118,12 +148,79 @@ func TestStartContainerFrom_Success(t *testing.T) {
 	api.On("StartContainer", "def789", mock.AnythingOfType("*dockerclient.HostConfig")).Return(nil).Once()
 
 	client := dockerClient{api: api}
-	err := client.StartContainerFrom(c)
+	r := NewRunResult(TriggerTimer, nil)
+	err := client.StartContainerFrom(c, r)
 
 	assert.NoError(t, err)
+	assert.Equal(t, "def789", r.ContainerID)
+	assert.Equal(t, "foo", r.CreatedFrom)
+	assert.True(t, strings.HasPrefix(r.ContainerName, "tugbot_foo_"))
+	assert.True(t, strings.HasSuffix(r.ContainerName, "_"+r.ID))
+	assert.False(t, r.StartedAt.IsZero())
 	api.AssertExpectations(t)
 }
 
+func TestRunConfig(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Name: "foo",
+			Config: &dockerclient.ContainerConfig{
+				Image:  "tests",
+				Env:    []string{"PATH=/bin", "TUGBOT_TRIGGER=stale"},
+				Labels: map[string]string{TugbotTest: "true"},
+			},
+		},
+	}
+	e := &dockerclient.Event{Type: "container", Action: "start", From: "voting-app:1.3", Actor: dockerclient.Actor{
+		ID: "abc123", Attributes: map[string]string{"name": "voting"}}}
+	r := NewRunResult(TriggerDocker, e)
+	config := runConfig(c, r)
+
+	assert.Equal(t, "tests", config.Image)
+	assert.Equal(t, "foo", config.Labels[TugbotCreatedFrom])
+	assert.Equal(t, []string{
+		"PATH=/bin",
+		"TUGBOT_TRIGGER=docker",
+		"TUGBOT_RUN_ID=" + r.ID,
+		"TUGBOT_EVENT_TYPE=container",
+		"TUGBOT_EVENT_ACTION=start",
+		"TUGBOT_EVENT_ACTOR_ID=abc123",
+		"TUGBOT_EVENT_ACTOR_NAME=voting",
+		"TUGBOT_EVENT_IMAGE=voting-app:1.3",
+	}, config.Env)
+	// test container config is not changed
+	assert.False(t, c.IsCreatedByTugbot())
+	assert.Equal(t, []string{"PATH=/bin", "TUGBOT_TRIGGER=stale"}, c.containerInfo.Config.Env)
+}
+
+func TestRunConfig_Overrides(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Name: "foo",
+			Config: &dockerclient.ContainerConfig{
+				Cmd:        []string{"ps", "-ef"},
+				Entrypoint: []string{"/bin/sh", "-c"},
+				WorkingDir: "/",
+				Env:        []string{"PATH=/bin", "MODE=noop"},
+				Labels: map[string]string{
+					TugbotRunCmd:        "java -jar mytests.jar",
+					TugbotRunEntrypoint: "[]",
+					TugbotRunEnv:        "MODE=full, TUGBOT_TRIGGER=wrong",
+					TugbotRunWorkdir:    "/tests",
+				},
+			},
+		},
+	}
+	r := NewRunResult(TriggerTimer, nil)
+	config := runConfig(c, r)
+
+	assert.Equal(t, []string{"java", "-jar", "mytests.jar"}, config.Cmd)
+	assert.Equal(t, []string{}, config.Entrypoint)
+	assert.Equal(t, "/tests", config.WorkingDir)
+	assert.Equal(t, []string{"PATH=/bin", "MODE=full", "TUGBOT_TRIGGER=timer", "TUGBOT_RUN_ID=" + r.ID}, config.Env)
+	assert.Equal(t, []string{"ps", "-ef"}, c.containerInfo.Config.Cmd)
+}
+
 func TestStartContainerFrom_CreateContainerError(t *testing.T) {
 	c := Container{
 		containerInfo: &dockerclient.ContainerInfo{
@@ -146,7 +243,7 @@ func TestStartContainerFrom_CreateContainerError(t *testing.T) {
 		}), mock.AnythingOfType("*dockerclient.AuthConfig")).Return("", errors.New("oops")).Once()
 
 	client := dockerClient{api: api}
-	err := client.StartContainerFrom(c)
+	err := client.StartContainerFrom(c, NewRunResult(TriggerTimer, nil))
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "oops")
@@ -175,11 +272,103 @@ func TestStartContainerFrom_StartContainerError(t *testing.T) {
 		}),
 		mock.AnythingOfType("*dockerclient.AuthConfig")).Return("created-container-id", nil).Once()
 	api.On("StartContainer", "created-container-id", mock.Anything).Return(errors.New("whoops")).Once()
+	api.On("RemoveContainer", "created-container-id", true, true).Return(nil).Once()
 
 	client := dockerClient{api: api}
-	err := client.StartContainerFrom(c)
+	err := client.StartContainerFrom(c, NewRunResult(TriggerTimer, nil))
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "whoops")
 	api.AssertExpectations(t)
 }
+
+func TestStartContainerFrom_StartContainerAndRemoveError(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Name:       "foo",
+			Config:     &dockerclient.ContainerConfig{},
+			HostConfig: &dockerclient.HostConfig{},
+		},
+		imageInfo: &dockerclient.ImageInfo{
+			Config: &dockerclient.ContainerConfig{},
+		},
+	}
+
+	api := mockclient.NewMockClient()
+	api.On("CreateContainer", mock.Anything, mock.Anything, mock.Anything).Return("created-container-id", nil).Once()
+	api.On("StartContainer", "created-container-id", mock.Anything).Return(errors.New("whoops")).Once()
+	api.On("RemoveContainer", "created-container-id", true, true).Return(errors.New("busy")).Once()
+
+	client := dockerClient{api: api}
+	r := NewRunResult(TriggerTimer, nil)
+	err := client.StartContainerFrom(c, r)
+
+	assert.EqualError(t, err, "whoops")
+	assert.Empty(t, r.ContainerID)
+	api.AssertExpectations(t)
+}
+
+func TestWaitContainer_Success(t *testing.T) {
+	ci := &dockerclient.ContainerInfo{
+		Id:     "def789",
+		Image:  "abc123",
+		Config: &dockerclient.ContainerConfig{Image: "img"},
+		State:  &dockerclient.State{ExitCode: 3, StartedAt: time.Now().Add(-time.Minute), FinishedAt: time.Now()},
+	}
+	wait := make(chan dockerclient.WaitResult, 1)
+	wait <- dockerclient.WaitResult{ExitCode: 3}
+	api := mockclient.NewMockClient()
+	api.On("Wait", "def789").Return((<-chan dockerclient.WaitResult)(wait)).Once()
+	api.On("InspectContainer", "def789").Return(ci, nil).Once()
+	api.On("InspectImage", "abc123").Return(&dockerclient.ImageInfo{}, nil).Once()
+
+	client := dockerClient{api: api}
+	c, err := client.WaitContainer("def789")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c.ExitCode())
+	assert.Equal(t, ci.State.FinishedAt, c.FinishedAt())
+	api.AssertExpectations(t)
+}
+
+func TestWaitContainer_WaitError(t *testing.T) {
+	wait := make(chan dockerclient.WaitResult, 1)
+	wait <- dockerclient.WaitResult{ExitCode: -1, Error: errors.New("oops")}
+	api := mockclient.NewMockClient()
+	api.On("Wait", "def789").Return((<-chan dockerclient.WaitResult)(wait)).Once()
+
+	client := dockerClient{api: api}
+	_, err := client.WaitContainer("def789")
+
+	assert.EqualError(t, err, "oops")
+	api.AssertExpectations(t)
+}
+
+func TestCopyFromContainer_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1.20/containers/def789/archive", r.URL.Path)
+		assert.Equal(t, "/var/tests/results", r.URL.Query().Get("path"))
+		w.Write([]byte("tar"))
+	}))
+	defer server.Close()
+
+	client := dockerClient{url: server.URL, httpClient: http.DefaultClient}
+	archive, err := client.CopyFromContainer("def789", "/var/tests/results")
+
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(archive)
+	archive.Close()
+	assert.Equal(t, "tar", string(data))
+}
+
+func TestCopyFromContainer_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := dockerClient{url: server.URL, httpClient: http.DefaultClient}
+	_, err := client.CopyFromContainer("def789", "/var/tests/results")
+
+	assert.Error(t, err)
+}
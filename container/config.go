@@ -0,0 +1,210 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/samalba/dockerclient"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is tugbot configuration file, declaring test containers that are not (or not
+// fully) configured by labels, for example test containers of third-party images.
+type Config struct {
+	Tests []TestDefinition `yaml:"tests"`
+}
+
+// TestDefinition declares a test container: containers matching Name and Image are
+// treated as test containers with labels derived from the definition. Container own
+// labels take precedence over the definition.
+type TestDefinition struct {
+	// Name matches container name: name, comma separated name list or RE2 regexp
+	Name string `yaml:"name"`
+	// Image matches container image name: name, comma separated name list or RE2 regexp
+	Image string `yaml:"image"`
+	// Docker subscribes to Docker events by filter name (type, action, container, image, label)
+	Docker map[string]string `yaml:"docker"`
+	// Debounce is the Docker events debounce window
+	Debounce string `yaml:"debounce"`
+	// Webhook subscribes to inbound webhook events by payload field name filters
+	Webhook map[string]string `yaml:"webhook"`
+	// Timer is the recurrent run interval
+	Timer string `yaml:"timer"`
+	// Cron is the run schedule cron expression
+	Cron string `yaml:"cron"`
+	// Startup runs test container once when tugbot starts
+	Startup bool `yaml:"startup"`
+	// Discovered runs test container once when discovered by tugbot
+	Discovered bool `yaml:"discovered"`
+	// Timeout is the run timeout
+	Timeout string `yaml:"timeout"`
+	// Labels are any other tugbot labels, for example: tugbot-results-dir
+	Labels map[string]string `yaml:"labels"`
+}
+
+// LoadConfig reads tugbot configuration file, YAML or JSON.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ret Config
+	if err := yaml.UnmarshalStrict(data, &ret); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s (%v)", file, err)
+	}
+	for i, test := range ret.Tests {
+		if err := test.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s, test #%d (%v)", file, i+1, err)
+		}
+	}
+
+	return &ret, nil
+}
+
+// Validate returns an error if test definition is invalid.
+func (d TestDefinition) Validate() error {
+	if d.Name == "" && d.Image == "" {
+		return fmt.Errorf("either name or image is required")
+	}
+	for filter := range d.Docker {
+		if !sliceContains(filter, []string{"type", "action", "container", "image", "label"}) {
+			return fmt.Errorf("unknown Docker event filter: %s", filter)
+		}
+	}
+
+	return validateLabels(d.labels())
+}
+
+// validateLabels returns an error if a trigger or timeout label value can not be parsed.
+func validateLabels(labels map[string]string) error {
+	if errs := newTestSpec(labels).Errors; len(errs) > 0 {
+		return errs[0]
+	}
+	c := Container{containerInfo: &dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{Labels: labels}}}
+	if val, ok := labels[TugbotTimeout]; ok {
+		if _, ok := c.GetTimeout(); !ok {
+			return fmt.Errorf("invalid %s label: %s", TugbotTimeout, val)
+		}
+	}
+
+	return nil
+}
+
+// Matches returns whether or not container (name and image) matches test definition.
+func (d TestDefinition) Matches(name string, image string) bool {
+	return (d.Name == "" || inFilterOrList(strings.TrimPrefix(name, "/"), d.Name)) &&
+		(d.Image == "" || inFilterOrList(image, d.Image))
+}
+
+// labels returns tugbot labels derived from test definition.
+func (d TestDefinition) labels() map[string]string {
+	ret := map[string]string{TugbotTest: "true"}
+	for k, v := range d.Labels {
+		ret[k] = v
+	}
+	if d.Docker != nil {
+		ret[TugbotEventDocker] = ""
+		for filter, val := range d.Docker {
+			ret[TugbotEventDocker+"-filter-"+filter] = val
+		}
+	}
+	if d.Webhook != nil {
+		ret[TugbotEventWebhook] = ""
+		for field, val := range d.Webhook {
+			ret[WebhookFilterPrefix+field] = val
+		}
+	}
+	for label, val := range map[string]string{
+		TugbotEventDockerDebounce: d.Debounce,
+		TugbotEventTimer:          d.Timer,
+		TugbotEventCron:           d.Cron,
+		TugbotTimeout:             d.Timeout,
+	} {
+		if val != "" {
+			ret[label] = val
+		}
+	}
+	if d.Startup {
+		ret[TugbotEventStartup] = ""
+	}
+	if d.Discovered {
+		ret[TugbotEventDiscovered] = ""
+	}
+
+	return ret
+}
+
+// TestDefinitions holds test definitions of tugbot configuration file, that can be replaced
+// (on reload) while in use, and test container overrides.
+type TestDefinitions struct {
+	mu        sync.RWMutex
+	tests     []TestDefinition
+	overrides []Override
+}
+
+// Set replaces test definitions.
+func (t *TestDefinitions) Set(tests []TestDefinition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tests = tests
+}
+
+// SetOverrides replaces test container overrides.
+func (t *TestDefinitions) SetOverrides(overrides []Override) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.overrides = overrides
+}
+
+// apply returns container info with labels of matching test definitions merged into
+// container labels and matching overrides applied on top of them, or info itself if
+// neither test definition nor override matches.
+func (t *TestDefinitions) apply(info *dockerclient.ContainerInfo) *dockerclient.ContainerInfo {
+	if t == nil || info.Config == nil {
+		return info
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var labels map[string]string
+	for _, test := range t.tests {
+		if test.Matches(info.Name, info.Config.Image) {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			// earlier definitions take precedence
+			for k, v := range test.labels() {
+				if _, ok := labels[k]; !ok {
+					labels[k] = v
+				}
+			}
+		}
+	}
+	name := strings.TrimPrefix(info.Name, "/")
+	for _, override := range t.overrides {
+		if override.Name == name && labels == nil {
+			labels = make(map[string]string)
+		}
+	}
+	if labels == nil {
+		return info
+	}
+	for k, v := range info.Config.Labels {
+		labels[k] = v
+	}
+	// later overrides take precedence
+	for _, override := range t.overrides {
+		if override.Name == name {
+			for k, v := range override.Labels {
+				labels[k] = v
+			}
+		}
+	}
+	ret := *info
+	config := *info.Config
+	config.Labels = labels
+	ret.Config = &config
+
+	return &ret
+}
@@ -0,0 +1,110 @@
+package container
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, name string, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "tugbot-config")
+	assert.NoError(t, err)
+	file := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+
+	return file, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	file, cleanup := writeConfig(t, "tugbot.yml", `
+tests:
+- name: api-tests
+  docker:
+    type: container
+    action: start
+  debounce: 30s
+  timeout: 5m
+- image: re2:^acme/
+  cron: "0 2 * * *"
+  startup: true
+  labels:
+    tugbot-results-dir: /results
+`)
+	defer cleanup()
+
+	config, err := LoadConfig(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []TestDefinition{
+		{Name: "api-tests", Docker: map[string]string{"type": "container", "action": "start"}, Debounce: "30s", Timeout: "5m"},
+		{Image: "re2:^acme/", Cron: "0 2 * * *", Startup: true, Labels: map[string]string{TugbotResultsDir: "/results"}},
+	}, config.Tests)
+}
+
+func TestLoadConfig_JSON(t *testing.T) {
+	file, cleanup := writeConfig(t, "tugbot.json", `{"tests": [{"name": "api-tests", "webhook": {"service": "api"}}]}`)
+	defer cleanup()
+
+	config, err := LoadConfig(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []TestDefinition{{Name: "api-tests", Webhook: map[string]string{"service": "api"}}}, config.Tests)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	for content, expected := range map[string]string{
+		"tests:\n- name: a\n  timer: soon\n":          "invalid tugbot-event-timer label: soon",
+		"tests:\n- name: a\n  cron: never\n":          "invalid tugbot-event-cron label: never",
+		"tests:\n- name: a\n  docker: {status: up}\n": "unknown Docker event filter: status",
+		"tests:\n- timeout: 1m\n":                     "either name or image is required",
+		"tests:\n- name: a\n  triggers: {}\n":         "field triggers not found",
+	} {
+		file, cleanup := writeConfig(t, "tugbot.yml", content)
+		_, err := LoadConfig(file)
+		cleanup()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
+
+func TestTestDefinitionMatches(t *testing.T) {
+	assert.True(t, TestDefinition{Name: "api-tests, ui-tests"}.Matches("/ui-tests", "acme/ui-tests"))
+	assert.False(t, TestDefinition{Name: "api-tests"}.Matches("/ui-tests", "acme/ui-tests"))
+	assert.True(t, TestDefinition{Name: "re2:-tests$", Image: "re2:^acme/"}.Matches("/ui-tests", "acme/ui-tests"))
+	assert.False(t, TestDefinition{Name: "re2:-tests$", Image: "re2:^acme/"}.Matches("/ui-tests", "other/ui-tests"))
+}
+
+func TestTestDefinitionsApply(t *testing.T) {
+	definitions := &TestDefinitions{}
+	definitions.Set([]TestDefinition{
+		{Name: "api-tests", Docker: map[string]string{"type": "container"}, Timer: "10m"},
+		{Image: "re2:^acme/", Timer: "1h", Webhook: map[string]string{}, Discovered: true},
+	})
+	info := &dockerclient.ContainerInfo{
+		Name:   "/api-tests",
+		Config: &dockerclient.ContainerConfig{Image: "acme/api-tests", Labels: map[string]string{TypeFilter: "image"}},
+	}
+
+	c := Container{containerInfo: definitions.apply(info)}
+
+	assert.Equal(t, map[string]string{
+		TugbotTest:            "true",
+		TugbotEventDocker:     "",
+		TypeFilter:            "image",
+		TugbotEventTimer:      "10m",
+		TugbotEventWebhook:    "",
+		TugbotEventDiscovered: "",
+	}, c.containerInfo.Config.Labels)
+	assert.Equal(t, map[string]string{TypeFilter: "image"}, info.Config.Labels)
+
+	// not matching
+	other := &dockerclient.ContainerInfo{Name: "/db", Config: &dockerclient.ContainerConfig{Image: "postgres"}}
+	assert.Equal(t, other, definitions.apply(other))
+	// no definitions
+	assert.Equal(t, info, (*TestDefinitions)(nil).apply(info))
+}
@@ -2,9 +2,11 @@ package container
 
 import (
 	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/cron"
 	"github.com/samalba/dockerclient"
 
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,13 +17,89 @@ const (
 	TugbotTest        = "tugbot-test"
 	TugbotEventDocker = "tugbot-event-docker"
 	TugbotEventTimer  = "tugbot-event-timer"
+	TugbotEventCron   = "tugbot-event-cron"
 	TugbotCreatedFrom = "tugbot-created-from"
 	SwarmTaskID       = "com.docker.swarm.task.id"
 )
 
+// Run once triggers (marker labels, no value is required)
+const (
+	// run once when tugbot starts
+	TugbotEventStartup = "tugbot-event-startup"
+	// run once when a new test container is discovered by tugbot
+	TugbotEventDiscovered = "tugbot-event-discovered"
+)
+
+// Test results
+const (
+	// test results directory, default: /var/tests/results
+	TugbotResultsDir = "tugbot-results-dir"
+	// test results format: junit|tap|mocha|gotest
+	TugbotResultsFormat = "tugbot-results-format"
+)
+
+// Concurrency policy, when a test container is triggered while previous run is still running
+const (
+	// concurrency policy: skip|queue|replace, default: skip
+	TugbotConcurrency = "tugbot-concurrency"
+	// ConcurrencySkip - skip new run
+	ConcurrencySkip = "skip"
+	// ConcurrencyQueue - start new run after previous run is finished
+	ConcurrencyQueue = "queue"
+	// ConcurrencyReplace - stop previous run and start new one
+	ConcurrencyReplace = "replace"
+)
+
+// Retention of containers created by tugbot
+const (
+	// number of tugbot created containers to keep (per test container)
+	TugbotKeepLast = "tugbot-keep-last"
+	// how long to keep tugbot created containers, use time suffix ("s", "m", "h")
+	TugbotKeepFor = "tugbot-keep-for"
+)
+
+// Run overrides, applied to test container config when tugbot runs it
+const (
+	// command: JSON array or whitespace separated arguments
+	TugbotRunCmd = "tugbot-run-cmd"
+	// entrypoint: JSON array or whitespace separated arguments
+	TugbotRunEntrypoint = "tugbot-run-entrypoint"
+	// environment variables: JSON array or comma separated KEY=value pairs
+	TugbotRunEnv = "tugbot-run-env"
+	// working directory
+	TugbotRunWorkdir = "tugbot-run-workdir"
+)
+
+// Timeout
+const (
+	// run timeout, use time suffix ("s", "m", "h"); test container is stopped (then killed) after timeout
+	TugbotTimeout = "tugbot-timeout"
+)
+
+// Retry policy, when a test container exits with non zero exit code
+const (
+	// number of times to retry a failed run, default: 0
+	TugbotRetryCount = "tugbot-retry-count"
+	// backoff before the first retry, doubled for each next retry; use time suffix ("s", "m", "h"), default: 10s
+	TugbotRetryBackoff = "tugbot-retry-backoff"
+)
+
+// DefaultRetryBackoff is the backoff before the first retry used when 'tugbot-retry-backoff' label is missing
+const DefaultRetryBackoff = time.Second * 10
+
+// Scheduling
+const (
+	// test container priority (int, default: 0), when max concurrent tests is reached
+	// queued runs of test containers with higher priority start first
+	TugbotPriority = "tugbot-priority"
+)
+
+// DefaultResultsDir is the test results directory used when 'tugbot-results-dir' label is missing
+const DefaultResultsDir = "/var/tests/results"
+
 // Docker Event Filter
 const (
-	// type filter: tugbot-event-docker-filter-type=container|image|daemon|network|volume|plugin
+	// type filter: tugbot-event-docker-filter-type=container|image|daemon|network|volume|plugin|service|node|secret|config
 	TypeFilter = "tugbot-event-docker-filter-type"
 	// action filter (depends on type), for 'container' type:
 	//  - attach, commit, copy, create, destroy, detach, die, exec_create, exec_detach, exec_start, export,
@@ -33,6 +111,18 @@ const (
 	ImageFilter = "tugbot-event-docker-filter-image"
 	// label filter: use key=value comma separated pairs
 	LabelFilter = "tugbot-event-docker-filter-label"
+	// debounce window: collapse matching events into a single run, use time suffix ("s", "m", "h")
+	TugbotEventDockerDebounce = "tugbot-event-docker-debounce"
+)
+
+// Webhook Event Filter
+const (
+	// marker label (no value is required): subscribe test container to inbound webhook events
+	TugbotEventWebhook = "tugbot-event-webhook"
+	// payload field filter prefix: tugbot-event-webhook-filter-<field>=<value>, where field is
+	// a payload field name (use '.' for nested fields) and value is a name, comma separated
+	// name list or RE2 regexp
+	WebhookFilterPrefix = "tugbot-event-webhook-filter-"
 )
 
 // NewContainer returns a new Container instance instantiated with the
@@ -79,6 +169,26 @@ func (c Container) ImageName() string {
 	return imageName
 }
 
+// Hostname returns the container host name.
+func (c Container) Hostname() string {
+	return c.containerInfo.Config.Hostname
+}
+
+// ExitCode returns the exit code of the last container run.
+func (c Container) ExitCode() int {
+	return c.containerInfo.State.ExitCode
+}
+
+// StartedAt returns the time the container was last started.
+func (c Container) StartedAt() time.Time {
+	return c.containerInfo.State.StartedAt
+}
+
+// FinishedAt returns the time the container last exited.
+func (c Container) FinishedAt() time.Time {
+	return c.containerInfo.State.FinishedAt
+}
+
 // IsTugbot returns whether or not the current container is the tugbot container itself.
 // The tugbot container is identified by the presence of the "tugbot.service"
 // label in the container metadata.
@@ -92,15 +202,15 @@ func (c Container) IsTugbot() bool {
 // A candidate container is identified by the presence of "tugbot-test",
 // it doesn't contain "tugbot.created.from" in the container metadata and it state is "Exited".
 func (c Container) IsTugbotCandidate() bool {
-	ret := false
+	return c.IsTugbotTest() && c.containerInfo.State.StateString() == "exited"
+}
+
+// IsTugbotTest returns whether or not a container is a test container, running or not:
+// it has "tugbot-test" label and it is not created by tugbot.
+func (c Container) IsTugbotTest() bool {
 	val, ok := c.containerInfo.Config.Labels[TugbotTest]
-	if ok && val == "true" {
-		if !c.IsCreatedByTugbot() {
-			ret = c.containerInfo.State.StateString() == "exited"
-		}
-	}
 
-	return ret
+	return ok && val == "true" && !c.IsCreatedByTugbot()
 }
 
 // IsCreatedByTugbot returns whether or not a container created by tugbot.
@@ -110,66 +220,315 @@ func (c Container) IsCreatedByTugbot() bool {
 	return ok && val != ""
 }
 
+// CreatedFrom returns the name of the test container this container created from, empty if not created by tugbot.
+func (c Container) CreatedFrom() string {
+	return c.containerInfo.Config.Labels[TugbotCreatedFrom]
+}
+
+// IsRunning returns whether or not the container is running.
+func (c Container) IsRunning() bool {
+	return c.containerInfo.State.Running
+}
+
+// IsStartupListener returns whether or not a container should run once when tugbot starts.
+func (c Container) IsStartupListener() bool {
+	return c.Spec().Startup
+}
+
+// IsDiscoveryListener returns whether or not a container should run once when discovered by tugbot.
+func (c Container) IsDiscoveryListener() bool {
+	return c.Spec().Discovered
+}
+
 // IsEventListener returns whether or not a container should run when an event e is occurred.
 func (c Container) IsEventListener(e *dockerclient.Event) bool {
-	ret := false
-	if e != nil {
-		// check if container is subscribed to Docker events, i.e. 'tugbot-event-docker' label exists
-		_, ret = c.containerInfo.Config.Labels[TugbotEventDocker]
-		if ret {
-			// filter by event type
-			if typeFilter, ok := c.containerInfo.Config.Labels[TypeFilter]; ok {
-				ret = sliceContains(e.Type, splitAndTrimSpaces(typeFilter, ","))
-			}
-			// filter by event action
-			if actionFilter, ok := c.containerInfo.Config.Labels[ActionFilter]; ok {
-				ret = ret && sliceContains(e.Action, splitAndTrimSpaces(actionFilter, ","))
-			}
-			// filter by container name or name regexp
-			if containerFilter, ok := c.containerInfo.Config.Labels[ContainerFilter]; ok {
-				ret = ret && inFilterOrList(e.Actor.Attributes["name"], containerFilter)
-			}
+	if e == nil {
+		return false
+	}
+	// check if container is subscribed to Docker events, i.e. 'tugbot-event-docker' label exists
+	docker := c.Spec().Docker
+
+	return docker != nil && docker.Match(e)
+}
+
+// ExplainEvent returns whether or not a container should run when an event e is occurred and,
+// if not, the reason: container is not subscribed to Docker events or a filter rejected the event.
+func (c Container) ExplainEvent(e *dockerclient.Event) (bool, string) {
+	docker := c.Spec().Docker
+	if docker == nil {
+		return false, fmt.Sprintf("not subscribed to Docker events, %s label is missing", TugbotEventDocker)
+	}
+	if filter := docker.Reject(e); filter != "" {
+		return false, fmt.Sprintf("rejected by %s filter: %s", filter, c.containerInfo.Config.Labels[filter])
+	}
+
+	return true, ""
+}
+
+// GetEventDockerFilters returns Docker event filters by filter name (type, action, container, image, label)
+// and true if test container is subscribed to Docker events, Otherwise false.
+func (c Container) GetEventDockerFilters() (map[string]string, bool) {
+	docker := c.Spec().Docker
+	if docker == nil {
+		return nil, false
+	}
+
+	return copyLabels(docker.Filters), true
+}
+
+// IsWebhookListener returns whether or not a container should run when an inbound webhook event
+// with payload is received: container must be subscribed to webhook events and all its
+// webhook filters must match payload fields.
+func (c Container) IsWebhookListener(payload map[string]string) bool {
+	webhook := c.Spec().Webhook
+
+	return webhook != nil && webhook.Match(payload)
+}
+
+// GetEventWebhookFilters returns webhook filters by payload field name and true
+// if test container is subscribed to webhook events, Otherwise false.
+func (c Container) GetEventWebhookFilters() (map[string]string, bool) {
+	webhook := c.Spec().Webhook
+	if webhook == nil {
+		return nil, false
+	}
+
+	return copyLabels(webhook.Filters), true
+}
+
+// GetEventDebounce returns the time window, during which matching Docker events are collapsed into
+// a single run, and true if docker label exist and label value parsed into Duration, Otherwise false.
+func (c Container) GetEventDebounce() (time.Duration, bool) {
+	if window := c.Spec().Debounce; window != nil {
+		return *window, true
+	}
+
+	return 0, false
+}
+
+// ResultsDir returns the directory where the test container saves test results.
+func (c Container) ResultsDir() string {
+	if dir, ok := c.containerInfo.Config.Labels[TugbotResultsDir]; ok && dir != "" {
+		return dir
+	}
+
+	return DefaultResultsDir
+}
+
+// ResultsFormat returns the test results format, empty if 'tugbot-results-format' label is missing.
+func (c Container) ResultsFormat() string {
+	return strings.ToLower(strings.TrimSpace(c.containerInfo.Config.Labels[TugbotResultsFormat]))
+}
+
+// GetEventListenerTimer returns interval duration between a test container run and true
+// if docker label exist and label value parsed into Duration, Otherwise false.
+func (c Container) GetEventListenerInterval() (time.Duration, bool) {
+	if interval := c.Spec().Timer; interval != nil {
+		return *interval, true
+	}
+
+	return 0, false
+}
+
+// GetEventCron returns the cron schedule of a test container run and true
+// if docker label exist and label value parsed into cron Schedule, Otherwise false.
+func (c Container) GetEventCron() (*cron.Schedule, bool) {
+	schedule := c.Spec().Cron
+
+	return schedule, schedule != nil
+}
+
+// Spec returns the trigger configuration parsed from container labels, parsed once
+// per container and cached by container ID. Label parse errors are logged when parsed.
+func (c Container) Spec() *TestSpec {
+	return specs.get(c.ID(), c.Name(), c.containerInfo.Config.Labels)
+}
+
+// GetKeepLast returns number of tugbot created containers to keep and true
+// if docker label exist and label value parsed into non negative int, Otherwise false.
+func (c Container) GetKeepLast() (int, bool) {
+	var ret int
+	val, ok := c.containerInfo.Config.Labels[TugbotKeepLast]
+	if ok {
+		keepLast, err := strconv.Atoi(val)
+		if err != nil || keepLast < 0 {
+			log.Errorf("Failed to parse %s docker label: %s into non negative int (%v)", TugbotKeepLast, val, err)
+			ok = false
+		} else {
+			ret = keepLast
 		}
-		// filter by event image
-		if imageFilter, ok := c.containerInfo.Config.Labels[ImageFilter]; ok {
-			// get image name from event.From field
-			imageName := e.From
-			// in case of "image" event.Type, event.ID contains image ID (name:tag) for 'pull' action and sha256:num for untag and delete
-			if e.Type == "image" {
-				imageName = e.ID
-			}
-			ret = ret && inFilterOrList(imageName, imageFilter)
+	}
+
+	return ret, ok
+}
+
+// GetKeepFor returns how long to keep tugbot created containers and true
+// if docker label exist and label value parsed into Duration, Otherwise false.
+func (c Container) GetKeepFor() (time.Duration, bool) {
+	var ret time.Duration
+	val, ok := c.containerInfo.Config.Labels[TugbotKeepFor]
+	if ok {
+		keepFor, err := time.ParseDuration(val)
+		if err != nil {
+			log.Errorf("Failed to parse %s docker label: %s into golang Duration (%v)", TugbotKeepFor, val, err)
+			ok = false
+		} else {
+			ret = keepFor
 		}
-		// filter by event labels
-		if labelFilter, ok := c.containerInfo.Config.Labels[LabelFilter]; ok {
-			labels := splitAndTrimSpaces(labelFilter, ",")
-			for _, label := range labels {
-				ret = ret && mapContains(e.Actor.Attributes, splitAndTrimSpaces(label, "="))
-			}
+	}
+
+	return ret, ok
+}
+
+// GetConcurrencyPolicy returns the policy applied when the test container is triggered
+// while its previous run is still running; default policy is 'skip'.
+func (c Container) GetConcurrencyPolicy() string {
+	val, ok := c.containerInfo.Config.Labels[TugbotConcurrency]
+	if ok {
+		switch val {
+		case ConcurrencySkip, ConcurrencyQueue, ConcurrencyReplace:
+			return val
 		}
+		log.Errorf("Unknown %s docker label value: %s, using '%s'", TugbotConcurrency, val, ConcurrencySkip)
 	}
 
-	return ret
+	return ConcurrencySkip
 }
 
-// GetEventListenerTimer returns interval duration between a test container run and true
+// GetTimeout returns the run timeout of a test container and true
 // if docker label exist and label value parsed into Duration, Otherwise false.
-func (c Container) GetEventListenerInterval() (time.Duration, bool) {
+func (c Container) GetTimeout() (time.Duration, bool) {
 	var ret time.Duration
-	val, ok := c.containerInfo.Config.Labels[TugbotEventTimer]
+	val, ok := c.containerInfo.Config.Labels[TugbotTimeout]
 	if ok {
-		interval, err := time.ParseDuration(val)
+		timeout, err := time.ParseDuration(val)
 		if err != nil {
-			log.Errorf("Failed to parse %s docker label: %s into golang Duration (%v)", TugbotEventTimer, val, err)
+			log.Errorf("Failed to parse %s docker label: %s into golang Duration (%v)", TugbotTimeout, val, err)
+			ok = false
+		} else {
+			ret = timeout
+		}
+	}
+
+	return ret, ok
+}
+
+// GetRetryCount returns the number of times to retry a failed run and true
+// if docker label exist and label value parsed into non negative int, Otherwise false.
+func (c Container) GetRetryCount() (int, bool) {
+	var ret int
+	val, ok := c.containerInfo.Config.Labels[TugbotRetryCount]
+	if ok {
+		count, err := strconv.Atoi(val)
+		if err != nil || count < 0 {
+			log.Errorf("Failed to parse %s docker label: %s into non negative int (%v)", TugbotRetryCount, val, err)
 			ok = false
 		} else {
-			ret = interval
+			ret = count
 		}
 	}
 
 	return ret, ok
 }
 
+// GetRetryBackoff returns the backoff before retry of a failed run attempt (starting from 1),
+// the backoff is doubled for each next retry; default backoff is used if docker label is missing
+// or failed to parse.
+func (c Container) GetRetryBackoff(attempt int) time.Duration {
+	ret := DefaultRetryBackoff
+	if val, ok := c.containerInfo.Config.Labels[TugbotRetryBackoff]; ok {
+		backoff, err := time.ParseDuration(val)
+		if err != nil || backoff < 0 {
+			log.Errorf("Failed to parse %s docker label: %s into non negative golang Duration (%v)", TugbotRetryBackoff, val, err)
+		} else {
+			ret = backoff
+		}
+	}
+	for i := 1; i < attempt && i < 16; i++ {
+		ret *= 2
+	}
+
+	return ret
+}
+
+// GetRunCmd returns the command to run test container with and true
+// if docker label exist and label value parsed into command, Otherwise false.
+func (c Container) GetRunCmd() ([]string, bool) {
+	return c.getRunArgs(TugbotRunCmd)
+}
+
+// GetRunEntrypoint returns the entrypoint to run test container with and true
+// if docker label exist and label value parsed into entrypoint, Otherwise false.
+func (c Container) GetRunEntrypoint() ([]string, bool) {
+	return c.getRunArgs(TugbotRunEntrypoint)
+}
+
+func (c Container) getRunArgs(label string) ([]string, bool) {
+	var ret []string
+	val, ok := c.containerInfo.Config.Labels[label]
+	if ok {
+		args, err := parseList(val, "")
+		if err != nil {
+			log.Errorf("Failed to parse %s docker label: %s into arguments (%v)", label, val, err)
+			ok = false
+		} else {
+			ret = args
+		}
+	}
+
+	return ret, ok
+}
+
+// GetRunEnv returns environment variables (KEY=value) to add to test container environment and true
+// if docker label exist and label value parsed into environment variables, Otherwise false.
+func (c Container) GetRunEnv() ([]string, bool) {
+	var ret []string
+	val, ok := c.containerInfo.Config.Labels[TugbotRunEnv]
+	if ok {
+		env, err := parseList(val, ",")
+		if err == nil {
+			for _, e := range env {
+				if !strings.Contains(e, "=") {
+					err = fmt.Errorf("missing '=' in %s", e)
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.Errorf("Failed to parse %s docker label: %s into environment variables (%v)", TugbotRunEnv, val, err)
+			ok = false
+		} else {
+			ret = env
+		}
+	}
+
+	return ret, ok
+}
+
+// GetRunWorkdir returns the working directory to run test container in and true
+// if docker label exist and is not empty, Otherwise false.
+func (c Container) GetRunWorkdir() (string, bool) {
+	val := strings.TrimSpace(c.containerInfo.Config.Labels[TugbotRunWorkdir])
+
+	return val, val != ""
+}
+
+// GetPriority returns the test container priority, default priority is 0.
+func (c Container) GetPriority() int {
+	var ret int
+	val, ok := c.containerInfo.Config.Labels[TugbotPriority]
+	if ok {
+		priority, err := strconv.Atoi(val)
+		if err != nil {
+			log.Errorf("Failed to parse %s docker label: %s into int (%v)", TugbotPriority, val, err)
+		} else {
+			ret = priority
+		}
+	}
+
+	return ret
+}
+
 // Any links in the HostConfig need to be re-written before they can be
 // re-submitted to the Docker create API.
 func (c Container) hostConfig() *dockerclient.HostConfig {
@@ -259,3 +259,388 @@ func TestGetEventListenerTimer_FailedToParseInterval(t *testing.T) {
 
 	assert.False(t, ok)
 }
+
+func TestIsStartupListener(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventStartup: ""},
+			},
+		},
+	}
+
+	assert.True(t, c.IsStartupListener())
+	assert.False(t, c.IsDiscoveryListener())
+}
+
+func TestIsDiscoveryListener(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventDiscovered: "true"},
+			},
+		},
+	}
+
+	assert.True(t, c.IsDiscoveryListener())
+	assert.False(t, c.IsStartupListener())
+}
+
+func TestGetEventCron(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventCron: "CRON_TZ=UTC 15 * * * *"},
+			},
+		},
+	}
+	schedule, ok := c.GetEventCron()
+
+	assert.True(t, ok)
+	assert.Equal(t,
+		time.Date(2016, time.October, 12, 11, 15, 0, 0, time.UTC),
+		schedule.Next(time.Date(2016, time.October, 12, 10, 20, 0, 0, time.UTC)))
+}
+
+func TestGetEventCron_FailedToParse(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventCron: "every minute"},
+			},
+		},
+	}
+	_, ok := c.GetEventCron()
+
+	assert.False(t, ok)
+}
+
+func TestResultsDir(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotResultsDir: "/tmp/results"},
+			},
+		},
+	}
+
+	assert.Equal(t, "/tmp/results", c.ResultsDir())
+}
+
+func TestResultsDir_Default(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{},
+		},
+	}
+
+	assert.Equal(t, DefaultResultsDir, c.ResultsDir())
+}
+
+func TestGetKeepLast(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotKeepLast: "5"},
+			},
+		},
+	}
+	keepLast, ok := c.GetKeepLast()
+
+	assert.True(t, ok)
+	assert.Equal(t, 5, keepLast)
+}
+
+func TestGetKeepLast_FailedToParse(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotKeepLast: "-1"},
+			},
+		},
+	}
+	_, ok := c.GetKeepLast()
+
+	assert.False(t, ok)
+}
+
+func TestGetKeepFor(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotKeepFor: "24h"},
+			},
+		},
+	}
+	keepFor, ok := c.GetKeepFor()
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour*24, keepFor)
+}
+
+func TestGetKeepFor_LabelNotFound(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{},
+		},
+	}
+	_, ok := c.GetKeepFor()
+
+	assert.False(t, ok)
+}
+
+func TestGetConcurrencyPolicy(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotConcurrency: ConcurrencyQueue},
+			},
+		},
+	}
+
+	assert.Equal(t, ConcurrencyQueue, c.GetConcurrencyPolicy())
+}
+
+func TestGetConcurrencyPolicy_Default(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotConcurrency: "parallel"},
+			},
+		},
+	}
+
+	assert.Equal(t, ConcurrencySkip, c.GetConcurrencyPolicy())
+}
+
+func TestGetEventDockerFilters(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{
+					TugbotEventDocker: "",
+					TypeFilter:        "container",
+					ActionFilter:      "start,stop",
+				},
+			},
+		},
+	}
+	filters, ok := c.GetEventDockerFilters()
+
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"type": "container", "action": "start,stop"}, filters)
+}
+
+func TestGetEventDockerFilters_NotSubscribed(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TypeFilter: "container"},
+			},
+		},
+	}
+	_, ok := c.GetEventDockerFilters()
+
+	assert.False(t, ok)
+}
+
+func TestIsWebhookListener(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{
+					TugbotEventWebhook:                     "",
+					WebhookFilterPrefix + "service":        "voting-app, result-app",
+					WebhookFilterPrefix + "deploy.version": "re2:^1\\.",
+				},
+			},
+		},
+	}
+
+	assert.True(t, c.IsWebhookListener(map[string]string{"service": "voting-app", "deploy.version": "1.2.0", "env": "prod"}))
+	assert.False(t, c.IsWebhookListener(map[string]string{"service": "worker", "deploy.version": "1.2.0"}))
+	assert.False(t, c.IsWebhookListener(map[string]string{"service": "voting-app", "deploy.version": "2.0.0"}))
+	assert.False(t, c.IsWebhookListener(map[string]string{"service": "voting-app"}))
+}
+
+func TestIsWebhookListener_NoFilters(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventWebhook: ""},
+			},
+		},
+	}
+
+	assert.True(t, c.IsWebhookListener(map[string]string{}))
+}
+
+func TestIsWebhookListener_NotSubscribed(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{WebhookFilterPrefix + "service": "voting-app"},
+			},
+		},
+	}
+
+	assert.False(t, c.IsWebhookListener(map[string]string{"service": "voting-app"}))
+	_, ok := c.GetEventWebhookFilters()
+	assert.False(t, ok)
+}
+
+func TestGetEventDebounce(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventDockerDebounce: "30s"},
+			},
+		},
+	}
+	window, ok := c.GetEventDebounce()
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Second*30, window)
+}
+
+func TestGetEventDebounce_FailedToParse(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotEventDockerDebounce: "30"},
+			},
+		},
+	}
+	_, ok := c.GetEventDebounce()
+
+	assert.False(t, ok)
+}
+
+func TestGetTimeout(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotTimeout: "10m"},
+			},
+		},
+	}
+	timeout, ok := c.GetTimeout()
+
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute*10, timeout)
+}
+
+func TestGetTimeout_FailedToParse(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotTimeout: "10"},
+			},
+		},
+	}
+	_, ok := c.GetTimeout()
+
+	assert.False(t, ok)
+}
+
+func TestGetRetryCount(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotRetryCount: "2"},
+			},
+		},
+	}
+	count, ok := c.GetRetryCount()
+
+	assert.True(t, ok)
+	assert.Equal(t, 2, count)
+}
+
+func TestGetRetryCount_Negative(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotRetryCount: "-1"},
+			},
+		},
+	}
+	_, ok := c.GetRetryCount()
+
+	assert.False(t, ok)
+}
+
+func TestGetRetryBackoff(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotRetryBackoff: "30s"},
+			},
+		},
+	}
+
+	assert.Equal(t, time.Second*30, c.GetRetryBackoff(1))
+	assert.Equal(t, time.Minute, c.GetRetryBackoff(2))
+	assert.Equal(t, time.Minute*2, c.GetRetryBackoff(3))
+}
+
+func TestGetRetryBackoff_Default(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotRetryBackoff: "soon"},
+			},
+		},
+	}
+
+	assert.Equal(t, DefaultRetryBackoff, c.GetRetryBackoff(1))
+}
+
+func TestGetPriority(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotPriority: "5"},
+			},
+		},
+	}
+
+	assert.Equal(t, 5, c.GetPriority())
+}
+
+func TestGetPriority_FailedToParse(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{TugbotPriority: "high"},
+			},
+		},
+	}
+
+	assert.Equal(t, 0, c.GetPriority())
+}
+
+func TestExplainEvent(t *testing.T) {
+	c := Container{
+		containerInfo: &dockerclient.ContainerInfo{
+			Id: "explain-event",
+			Config: &dockerclient.ContainerConfig{
+				Labels: map[string]string{
+					TugbotEventDocker: "",
+					TypeFilter:        "container",
+					LabelFilter:       "env=ci",
+				},
+			},
+		},
+	}
+
+	ok, reason := c.ExplainEvent(&dockerclient.Event{Type: "container", Actor: dockerclient.Actor{Attributes: map[string]string{"env": "ci"}}})
+	assert.True(t, ok)
+	assert.Empty(t, reason)
+	ok, reason = c.ExplainEvent(&dockerclient.Event{Type: "image"})
+	assert.False(t, ok)
+	assert.Equal(t, "rejected by tugbot-event-docker-filter-type filter: container", reason)
+	ok, reason = c.ExplainEvent(&dockerclient.Event{Type: "container"})
+	assert.False(t, ok)
+	assert.Equal(t, "rejected by tugbot-event-docker-filter-label filter: env=ci", reason)
+}
@@ -3,8 +3,56 @@ package container
 import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/samalba/dockerclient"
+
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is the type of events emitted by tugbot, published to webhooks in Docker event format
+const EventType = "tugbot"
+
+// Tugbot test container run lifecycle event actions
+const (
+	EventTestTriggered     = "test.triggered"
+	EventTestStarted       = "test.started"
+	EventTestFinished      = "test.finished"
+	EventTestTimeout       = "test.timeout"
+	EventTestFailedToStart = "test.failed-to-start"
+	EventTestSkipped       = "test.skipped"
+	EventTestReplaced      = "test.replaced"
 )
 
+// EventTestMisconfigured is the action of tugbot event warning about test container misconfiguration
+const EventTestMisconfigured = "test.misconfigured"
+
+// EventFilter is a compiled Docker event filter.
+type EventFilter struct {
+	// Filters map filter name (TypeFilter, ActionFilter, ContainerFilter, ImageFilter,
+	// LabelFilter) to filter value; other keys are ignored.
+	Filters map[string]string
+	matcher eventMatcher
+}
+
+// NewEventFilter compiles filters into a new EventFilter, returning an error if a filter
+// value can not be parsed (e.g. invalid RE2 regexp).
+func NewEventFilter(filters map[string]string) (EventFilter, error) {
+	matcher, errs := compileEventFilter(filters)
+	if len(errs) > 0 {
+		return EventFilter{}, errs[0]
+	}
+
+	return EventFilter{Filters: filters, matcher: matcher}, nil
+}
+
+// Match returns whether or not event e passes all filters, empty filter matches any event.
+func (f EventFilter) Match(e *dockerclient.Event) bool {
+	return f.matcher.match(e)
+}
+
 // IsCreatedByTugbot - true if created by tugbot
 func IsCreatedByTugbot(e *dockerclient.Event) bool {
 	ret := false
@@ -26,3 +74,96 @@ func IsSwarmTask(e *dockerclient.Event) bool {
 
 	return ret
 }
+
+// NewRunEvent returns a new tugbot event with action about test container run r.
+func NewRunEvent(action string, r RunResult) *dockerclient.Event {
+	now := time.Now()
+	attributes := map[string]string{
+		"name":    r.CreatedFrom,
+		"run-id":  r.ID,
+		"trigger": r.Trigger,
+		"status":  r.Status(),
+		"attempt": strconv.Itoa(r.Attempt),
+	}
+	if r.ContainerName != "" {
+		attributes["container"] = r.ContainerName
+	}
+	if !r.StartedAt.IsZero() {
+		attributes["duration"] = r.Duration().String()
+	}
+	if r.Finished() {
+		attributes["exit-code"] = strconv.Itoa(r.ExitCode)
+		attributes["final"] = strconv.FormatBool(r.Final)
+	}
+	if r.Error != "" {
+		attributes["error"] = r.Error
+	}
+
+	return &dockerclient.Event{
+		Status:   action,
+		ID:       r.ContainerID,
+		From:     r.ImageName,
+		Type:     EventType,
+		Action:   action,
+		Actor:    dockerclient.Actor{ID: r.ContainerID, Attributes: attributes},
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+}
+
+// NewMisconfiguredEvent returns a new tugbot event warning about test container c misconfiguration errs.
+func NewMisconfiguredEvent(c Container, errs []error) *dockerclient.Event {
+	now := time.Now()
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return &dockerclient.Event{
+		Status: EventTestMisconfigured,
+		ID:     c.ID(),
+		From:   c.ImageName(),
+		Type:   EventType,
+		Action: EventTestMisconfigured,
+		Actor: dockerclient.Actor{ID: c.ID(), Attributes: map[string]string{
+			"name":  c.Name(),
+			"error": strings.Join(messages, "; "),
+		}},
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+}
+
+// ReadEvents reads Docker events in JSON format, for example recorded by
+// 'docker events --format "{{json .}}"': an event, a sequence of events or JSON array of events.
+func ReadEvents(r io.Reader) ([]*dockerclient.Event, error) {
+	var ret []*dockerclient.Event
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid Docker event (%v)", err)
+		}
+		events := []*dockerclient.Event{}
+		if err := json.Unmarshal(raw, &events); err != nil {
+			var e dockerclient.Event
+			if err := json.Unmarshal(raw, &e); err != nil {
+				return nil, fmt.Errorf("invalid Docker event (%v)", err)
+			}
+			events = []*dockerclient.Event{&e}
+		}
+		if string(raw) == "null" {
+			events = []*dockerclient.Event{nil}
+		}
+		for _, e := range events {
+			if e == nil {
+				return nil, fmt.Errorf("invalid Docker event (null)")
+			}
+		}
+		ret = append(ret, events...)
+	}
+
+	return ret, nil
+}
@@ -1,7 +1,10 @@
 package container
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samalba/dockerclient"
 	"github.com/stretchr/testify/assert"
@@ -44,3 +47,153 @@ func TestIsSwarmTask_NoAttributes(t *testing.T) {
 	created := IsSwarmTask(&dockerclient.Event{})
 	assert.False(t, created)
 }
+
+func TestNewRunEvent(t *testing.T) {
+	finished := time.Now()
+	r := RunResult{
+		ID:            "run1",
+		Trigger:       TriggerTimer,
+		Attempt:       1,
+		CreatedFrom:   "api-tests",
+		ContainerID:   "abc123",
+		ContainerName: "tugbot_api-tests_20161012102030",
+		ImageName:     "tests:latest",
+		ExitCode:      137,
+		StartedAt:     finished.Add(-time.Minute),
+		FinishedAt:    finished,
+		TimedOut:      true,
+	}
+	e := NewRunEvent(EventTestTimeout, r)
+
+	assert.Equal(t, EventType, e.Type)
+	assert.Equal(t, EventTestTimeout, e.Action)
+	assert.Equal(t, EventTestTimeout, e.Status)
+	assert.Equal(t, "abc123", e.ID)
+	assert.Equal(t, "tests:latest", e.From)
+	assert.Equal(t, map[string]string{
+		"name":      "api-tests",
+		"run-id":    "run1",
+		"trigger":   TriggerTimer,
+		"status":    StatusTimeout,
+		"attempt":   "1",
+		"container": "tugbot_api-tests_20161012102030",
+		"duration":  "1m0s",
+		"exit-code": "137",
+		"final":     "false",
+	}, e.Actor.Attributes)
+	assert.NotZero(t, e.TimeNano)
+}
+
+func TestNewRunEvent_Triggered(t *testing.T) {
+	r := NewRunResult(TriggerCron, nil)
+	r.CreatedFrom = "api-tests"
+	e := NewRunEvent(EventTestTriggered, *r)
+
+	assert.Equal(t, EventTestTriggered, e.Action)
+	assert.Empty(t, e.ID)
+	assert.Equal(t, map[string]string{
+		"name":    "api-tests",
+		"run-id":  r.ID,
+		"trigger": TriggerCron,
+		"status":  StatusRunning,
+		"attempt": "1",
+	}, e.Actor.Attributes)
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	filter, err := NewEventFilter(map[string]string{
+		TypeFilter:      "container",
+		ActionFilter:    "start, die",
+		ContainerFilter: "re2:^voting",
+		LabelFilter:     "env=prod",
+	})
+	assert.NoError(t, err)
+	e := &dockerclient.Event{
+		Type:   "container",
+		Action: "start",
+		From:   "voting-app:latest",
+		Actor:  dockerclient.Actor{Attributes: map[string]string{"name": "voting-app", "env": "prod"}},
+	}
+
+	assert.True(t, filter.Match(e))
+	e.Action = "exec_start: /bin/sh"
+	assert.False(t, filter.Match(e))
+	e.Action = "die"
+	e.Actor.Attributes["env"] = "dev"
+	assert.False(t, filter.Match(e))
+}
+
+func TestEventFilterMatch_Image(t *testing.T) {
+	filter, err := NewEventFilter(map[string]string{ImageFilter: "re2:^redis"})
+	assert.NoError(t, err)
+
+	assert.True(t, filter.Match(&dockerclient.Event{Type: "container", From: "redis:3"}))
+	assert.True(t, filter.Match(&dockerclient.Event{Type: "image", ID: "redis:3"}))
+	assert.False(t, filter.Match(&dockerclient.Event{Type: "image", ID: "nginx:1", From: "redis:3"}))
+}
+
+func TestEventFilterMatch_Empty(t *testing.T) {
+	assert.True(t, EventFilter{}.Match(&dockerclient.Event{Type: "network", Action: "connect"}))
+}
+
+func TestNewEventFilter_Invalid(t *testing.T) {
+	_, err := NewEventFilter(map[string]string{ContainerFilter: "re2:^voting("})
+	assert.Error(t, err)
+}
+
+func TestNewMisconfiguredEvent(t *testing.T) {
+	c := NewContainer(&dockerclient.ContainerInfo{
+		Id:     "abc123",
+		Name:   "/api-tests",
+		Config: &dockerclient.ContainerConfig{Image: "tests:latest"},
+	}, nil)
+	e := NewMisconfiguredEvent(*c, []error{errors.New("unknown label tugbot-notify"), errors.New("invalid tugbot-timeout label: soon")})
+
+	assert.Equal(t, EventType, e.Type)
+	assert.Equal(t, EventTestMisconfigured, e.Action)
+	assert.Equal(t, "abc123", e.ID)
+	assert.Equal(t, "tests:latest", e.From)
+	assert.Equal(t, map[string]string{
+		"name":  "api-tests",
+		"error": "unknown label tugbot-notify; invalid tugbot-timeout label: soon",
+	}, e.Actor.Attributes)
+}
+
+func TestReadEvents(t *testing.T) {
+	events, err := ReadEvents(strings.NewReader(`{"status":"start","id":"abc","from":"nginx:latest","Type":"container","Action":"start",` +
+		`"Actor":{"ID":"abc","Attributes":{"image":"nginx:latest","name":"web"}},"time":1476267630,"timeNano":1476267630000000000}
+{"Type":"image","Action":"pull","id":"redis:3"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*dockerclient.Event{
+		{
+			Status:   "start",
+			ID:       "abc",
+			From:     "nginx:latest",
+			Type:     "container",
+			Action:   "start",
+			Actor:    dockerclient.Actor{ID: "abc", Attributes: map[string]string{"image": "nginx:latest", "name": "web"}},
+			Time:     1476267630,
+			TimeNano: 1476267630000000000,
+		},
+		{Type: "image", Action: "pull", ID: "redis:3"},
+	}, events)
+}
+
+func TestReadEvents_Array(t *testing.T) {
+	events, err := ReadEvents(strings.NewReader(`[{"Type":"container","Action":"start"},{"Type":"container","Action":"die"}]`))
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestReadEvents_Invalid(t *testing.T) {
+	_, err := ReadEvents(strings.NewReader(`{"Type":`))
+
+	assert.Error(t, err)
+	for _, input := range []string{`null`, `[null]`, `{"Type":"container"}
+null`} {
+		_, err = ReadEvents(strings.NewReader(input))
+		assert.EqualError(t, err, "invalid Docker event (null)", input)
+	}
+}
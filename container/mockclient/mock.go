@@ -1,6 +1,9 @@
 package mockclient
 
 import (
+	"io"
+	"time"
+
 	"github.com/gaia-docker/tugbot/container"
 	"github.com/samalba/dockerclient"
 	"github.com/stretchr/testify/mock"
@@ -20,8 +23,33 @@ func (m *MockClient) ListContainers(cf container.Filter) ([]container.Container,
 	return args.Get(0).([]container.Container), args.Error(1)
 }
 
-func (m *MockClient) StartContainerFrom(c container.Container) error {
-	args := m.Called(c)
+func (m *MockClient) StartContainerFrom(c container.Container, r *container.RunResult) error {
+	args := m.Called(c, r)
+	return args.Error(0)
+}
+
+func (m *MockClient) WaitContainer(containerID string) (*container.Container, error) {
+	args := m.Called(containerID)
+	return args.Get(0).(*container.Container), args.Error(1)
+}
+
+func (m *MockClient) CopyFromContainer(containerID, path string) (io.ReadCloser, error) {
+	args := m.Called(containerID, path)
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (m *MockClient) StopContainer(containerID string, timeout time.Duration) error {
+	args := m.Called(containerID, timeout)
+	return args.Error(0)
+}
+
+func (m *MockClient) KillContainer(containerID string) error {
+	args := m.Called(containerID)
+	return args.Error(0)
+}
+
+func (m *MockClient) RemoveContainer(containerID string) error {
+	args := m.Called(containerID)
 	return args.Error(0)
 }
 
@@ -0,0 +1,74 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// overrideAliases are short setting names of test container override, mapped to labels
+var overrideAliases = map[string]string{
+	"results":        TugbotResultsDir,
+	"results.format": TugbotResultsFormat,
+	"event.docker":   TugbotEventDocker,
+	"event.timer":    TugbotEventTimer,
+	"event.cron":     TugbotEventCron,
+}
+
+// Override overrides labels of test container Name at runtime, labels are applied on
+// top of the test container own labels.
+type Override struct {
+	Name   string
+	Labels map[string]string
+}
+
+// ParseOverride parses test container override: semicolon separated list of
+// setting=value pairs, where 'name' setting is the test container name and other
+// settings are tugbot labels, with or without 'tugbot-' prefix ('.' can be used
+// instead of '-'), for example: 'name=selenium_tests;results=/var/log/results;event.docker=create'.
+// Short setting names: results, results.format, event.timer, event.cron and event.docker,
+// which value is a comma separated list of Docker event actions. Unknown settings are rejected.
+func ParseOverride(val string) (Override, error) {
+	ret := Override{Labels: map[string]string{TugbotTest: "true"}}
+	for _, setting := range splitAndTrimSpaces(val, ";") {
+		if setting == "" {
+			continue
+		}
+		kv := strings.SplitN(setting, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		value := ""
+		if len(kv) == 2 {
+			value = strings.TrimSpace(kv[1])
+		}
+		if key == "name" {
+			ret.Name = strings.TrimPrefix(value, "/")
+			continue
+		}
+		label, ok := overrideAliases[key]
+		if !ok {
+			label = strings.Replace(key, ".", "-", -1)
+			if !strings.HasPrefix(label, "tugbot-") {
+				label = "tugbot-" + label
+			}
+			if !sliceContains(label, labelNames) && !strings.HasPrefix(label, WebhookFilterPrefix) {
+				if known := similarLabel(label); known != "" {
+					return ret, fmt.Errorf("invalid test container override %s, unknown setting %s, did you mean %s?", val, key, known)
+				}
+				return ret, fmt.Errorf("invalid test container override %s, unknown setting %s", val, key)
+			}
+		}
+		if label == TugbotEventDocker && value != "" {
+			// Docker event actions
+			ret.Labels[ActionFilter] = value
+			value = ""
+		}
+		ret.Labels[label] = value
+	}
+	if ret.Name == "" {
+		return ret, fmt.Errorf("invalid test container override %s, name is required", val)
+	}
+	if err := validateLabels(ret.Labels); err != nil {
+		return ret, fmt.Errorf("invalid test container override %s (%v)", val, err)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,71 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverride(t *testing.T) {
+	override, err := ParseOverride("name=selenium_tests;results=/var/log/results;event.docker=create")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Override{Name: "selenium_tests", Labels: map[string]string{
+		TugbotTest:        "true",
+		TugbotResultsDir:  "/var/log/results",
+		TugbotEventDocker: "",
+		ActionFilter:      "create",
+	}}, override)
+}
+
+func TestParseOverride_Labels(t *testing.T) {
+	override, err := ParseOverride(" name = /docker_bench ; event.timer=4h30m; tugbot-timeout=1h;event.docker.filter.type=container;event.startup")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Override{Name: "docker_bench", Labels: map[string]string{
+		TugbotTest:         "true",
+		TugbotEventTimer:   "4h30m",
+		TugbotTimeout:      "1h",
+		TypeFilter:         "container",
+		TugbotEventStartup: "",
+	}}, override)
+}
+
+func TestParseOverride_Invalid(t *testing.T) {
+	_, err := ParseOverride("results=/var/log/results")
+	assert.EqualError(t, err, "invalid test container override results=/var/log/results, name is required")
+	_, err = ParseOverride("name=bench;event.timer=often")
+	assert.EqualError(t, err, "invalid test container override name=bench;event.timer=often (invalid tugbot-event-timer label: often (time: invalid duration \"often\"))")
+	_, err = ParseOverride("name=bench;event.timeout=1h")
+	assert.EqualError(t, err, "invalid test container override name=bench;event.timeout=1h, unknown setting event.timeout")
+	_, err = ParseOverride("name=bench;retry.cont=2")
+	assert.EqualError(t, err, "invalid test container override name=bench;retry.cont=2, unknown setting retry.cont, did you mean tugbot-retry-count?")
+}
+
+func TestTestDefinitionsApply_Overrides(t *testing.T) {
+	definitions := &TestDefinitions{}
+	definitions.Set([]TestDefinition{{Name: "bench", Timer: "1h", Timeout: "5m"}})
+	override, _ := ParseOverride("name=bench;event.timer=4h30m")
+	definitions.SetOverrides([]Override{override})
+	info := &dockerclient.ContainerInfo{
+		Name:   "/bench",
+		Config: &dockerclient.ContainerConfig{Labels: map[string]string{TugbotTest: "false", TugbotEventTimer: "2h"}},
+	}
+
+	c := Container{containerInfo: definitions.apply(info)}
+
+	interval, _ := c.GetEventListenerInterval()
+	assert.Equal(t, time.Hour*4+time.Minute*30, interval)
+	timeout, _ := c.GetTimeout()
+	assert.Equal(t, time.Minute*5, timeout)
+	assert.Equal(t, "true", c.containerInfo.Config.Labels[TugbotTest])
+
+	// override only
+	definitions.Set(nil)
+	c = Container{containerInfo: definitions.apply(info)}
+	_, ok := c.GetTimeout()
+	assert.False(t, ok)
+	assert.Equal(t, "4h30m", c.containerInfo.Config.Labels[TugbotEventTimer])
+}
@@ -0,0 +1,191 @@
+package container
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/samalba/dockerclient"
+)
+
+// Run triggers
+const (
+	TriggerDocker = "docker"
+	TriggerTimer  = "timer"
+	TriggerCron   = "cron"
+	// TriggerStartup - run once on tugbot startup
+	TriggerStartup = "startup"
+	// TriggerDiscovered - run once when a new test container is discovered
+	TriggerDiscovered = "discovered"
+	// TriggerAPI - run requested via tugbot HTTP API
+	TriggerAPI = "api"
+	// TriggerWebhook - run triggered by inbound webhook event
+	TriggerWebhook = "webhook"
+)
+
+// Run statuses
+const (
+	StatusRunning = "running"
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
+	// StatusPassedAfterRetry - passed, but not on the first attempt
+	StatusPassedAfterRetry = "passed-after-retry"
+	// StatusSkipped - not started, skipped by concurrency policy
+	StatusSkipped = "skipped"
+	// StatusReplaced - not started, replaced by a newer run by concurrency policy
+	StatusReplaced = "replaced"
+)
+
+// Environment variables, that describe the run context, passed into test containers created by tugbot
+const (
+	EnvTrigger        = "TUGBOT_TRIGGER"
+	EnvRunID          = "TUGBOT_RUN_ID"
+	EnvEventType      = "TUGBOT_EVENT_TYPE"
+	EnvEventAction    = "TUGBOT_EVENT_ACTION"
+	EnvEventActorID   = "TUGBOT_EVENT_ACTOR_ID"
+	EnvEventActorName = "TUGBOT_EVENT_ACTOR_NAME"
+	EnvEventImage     = "TUGBOT_EVENT_IMAGE"
+)
+
+// RunResult represents a single run of a test container created by tugbot.
+// Event is the Docker event, that triggered the run; Events contains all Docker
+// events coalesced into a single run during debounce window. Payload is the
+// (flattened) payload of the inbound webhook event, that triggered the run.
+// Attempt is the run attempt number (starting from 1), failed runs are retried
+// according to the test container retry policy: RetryOf is the ID of the first
+// attempt and Final is set on the last attempt, which result is the final verdict.
+// Dropped is the status (skipped or replaced) of a run dropped by concurrency policy
+// before it started.
+type RunResult struct {
+	ID            string
+	Trigger       string
+	Attempt       int
+	RetryOf       string `json:",omitempty"`
+	Final         bool
+	Event         *dockerclient.Event
+	Events        []*dockerclient.Event `json:",omitempty"`
+	Payload       map[string]string     `json:",omitempty"`
+	CreatedFrom   string
+	ContainerID   string
+	ContainerName string
+	ImageName     string
+	ExitCode      int
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	TimedOut      bool   `json:",omitempty"`
+	Error         string `json:",omitempty"`
+	Dropped       string `json:",omitempty"`
+}
+
+// NewRunResult returns a new RunResult for a test container run triggered by
+// trigger. The Docker event e is nil unless the run was triggered by a Docker event.
+func NewRunResult(trigger string, e *dockerclient.Event) *RunResult {
+	return &RunResult{
+		ID:      newRunID(),
+		Trigger: trigger,
+		Attempt: 1,
+		Event:   e,
+	}
+}
+
+// Retry returns a new RunResult for the next attempt of the run.
+func (r RunResult) Retry() *RunResult {
+	retryOf := r.RetryOf
+	if retryOf == "" {
+		retryOf = r.ID
+	}
+
+	return &RunResult{
+		ID:      newRunID(),
+		Trigger: r.Trigger,
+		Attempt: r.Attempt + 1,
+		RetryOf: retryOf,
+		Event:   r.Event,
+		Events:  r.Events,
+		Payload: r.Payload,
+	}
+}
+
+// Env returns environment variables (KEY=value), that describe the run trigger and
+// the Docker event, that triggered the run (if any).
+func (r RunResult) Env() []string {
+	env := map[string]string{
+		EnvTrigger: r.Trigger,
+		EnvRunID:   r.ID,
+	}
+	if e := r.Event; e != nil {
+		env[EnvEventType] = e.Type
+		env[EnvEventAction] = e.Action
+		if env[EnvEventAction] == "" {
+			env[EnvEventAction] = e.Status
+		}
+		env[EnvEventActorID] = e.Actor.ID
+		if env[EnvEventActorID] == "" {
+			env[EnvEventActorID] = e.ID
+		}
+		env[EnvEventActorName] = e.Actor.Attributes["name"]
+		// like in Docker event filter: image event ID is image name, otherwise it's in event From field
+		env[EnvEventImage] = e.From
+		if e.Type == "image" {
+			env[EnvEventImage] = e.ID
+		}
+	}
+	ret := make([]string, 0, len(env))
+	for _, key := range []string{EnvTrigger, EnvRunID, EnvEventType, EnvEventAction, EnvEventActorID, EnvEventActorName, EnvEventImage} {
+		if val, ok := env[key]; ok {
+			ret = append(ret, key+"="+val)
+		}
+	}
+
+	return ret
+}
+
+// Finished returns whether or not the test container has exited.
+func (r RunResult) Finished() bool {
+	return !r.FinishedAt.IsZero()
+}
+
+// Passed returns whether or not the test container exited with zero exit code.
+func (r RunResult) Passed() bool {
+	return r.Finished() && r.Error == "" && !r.TimedOut && r.ExitCode == 0
+}
+
+// Status returns the test container run status.
+func (r RunResult) Status() string {
+	switch {
+	case r.Dropped != "":
+		return r.Dropped
+	case r.Error != "":
+		return StatusError
+	case !r.Finished():
+		return StatusRunning
+	case r.TimedOut:
+		return StatusTimeout
+	case r.ExitCode != 0:
+		return StatusFailed
+	case r.Attempt > 1:
+		return StatusPassedAfterRetry
+	}
+
+	return StatusPassed
+}
+
+// Duration returns the test container run duration.
+func (r RunResult) Duration() time.Duration {
+	if !r.Finished() {
+		return time.Since(r.StartedAt)
+	}
+
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+
+	return hex.EncodeToString(b)
+}
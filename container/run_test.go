@@ -0,0 +1,96 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunResult(t *testing.T) {
+	e := &dockerclient.Event{Type: "container", Action: "start"}
+	r := NewRunResult(TriggerDocker, e)
+
+	assert.NotEmpty(t, r.ID)
+	assert.Equal(t, TriggerDocker, r.Trigger)
+	assert.Equal(t, e, r.Event)
+	assert.False(t, r.Finished())
+}
+
+func TestNewRunResult_UniqueID(t *testing.T) {
+	assert.NotEqual(t, NewRunResult(TriggerTimer, nil).ID, NewRunResult(TriggerTimer, nil).ID)
+}
+
+func TestRunResultPassed_True(t *testing.T) {
+	now := time.Now()
+	r := RunResult{StartedAt: now.Add(-time.Second), FinishedAt: now}
+
+	assert.True(t, r.Passed())
+	assert.Equal(t, time.Second, r.Duration())
+}
+
+func TestRunResultPassed_NonZeroExitCode(t *testing.T) {
+	r := RunResult{StartedAt: time.Now(), FinishedAt: time.Now(), ExitCode: 1}
+
+	assert.False(t, r.Passed())
+}
+
+func TestRunResultPassed_NotFinished(t *testing.T) {
+	r := RunResult{StartedAt: time.Now()}
+
+	assert.False(t, r.Passed())
+}
+
+func TestRunResultStatus(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, StatusRunning, RunResult{StartedAt: now}.Status())
+	assert.Equal(t, StatusPassed, RunResult{StartedAt: now, FinishedAt: now}.Status())
+	assert.Equal(t, StatusFailed, RunResult{StartedAt: now, FinishedAt: now, ExitCode: 1}.Status())
+	assert.Equal(t, StatusError, RunResult{FinishedAt: now, Error: "whoops"}.Status())
+	assert.Equal(t, StatusTimeout, RunResult{StartedAt: now, FinishedAt: now, ExitCode: 137, TimedOut: true}.Status())
+	assert.Equal(t, StatusSkipped, RunResult{Dropped: StatusSkipped}.Status())
+}
+
+func TestRunResultEnv_Timer(t *testing.T) {
+	r := NewRunResult(TriggerTimer, nil)
+
+	assert.Equal(t, []string{"TUGBOT_TRIGGER=timer", "TUGBOT_RUN_ID=" + r.ID}, r.Env())
+}
+
+func TestRunResultEnv_ImageEvent(t *testing.T) {
+	// pre 1.10 Docker API event: no type, action and actor
+	r := NewRunResult(TriggerDocker, &dockerclient.Event{Status: "pull", ID: "redis:3"})
+
+	assert.Contains(t, r.Env(), "TUGBOT_EVENT_ACTION=pull")
+	assert.Contains(t, r.Env(), "TUGBOT_EVENT_ACTOR_ID=redis:3")
+
+	r = NewRunResult(TriggerDocker, &dockerclient.Event{Type: "image", Action: "pull", ID: "redis:3"})
+	assert.Contains(t, r.Env(), "TUGBOT_EVENT_IMAGE=redis:3")
+}
+
+func TestRunResultRetry(t *testing.T) {
+	e := &dockerclient.Event{Type: "container", Action: "start"}
+	first := NewRunResult(TriggerDocker, e)
+	first.ContainerID = "first"
+	first.ExitCode = 1
+	second := first.Retry()
+	third := second.Retry()
+
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, 2, second.Attempt)
+	assert.Equal(t, 3, third.Attempt)
+	assert.Equal(t, first.ID, second.RetryOf)
+	assert.Equal(t, first.ID, third.RetryOf)
+	assert.NotEqual(t, second.ID, third.ID)
+	assert.Equal(t, e, third.Event)
+	assert.Equal(t, TriggerDocker, third.Trigger)
+	assert.Empty(t, third.ContainerID)
+	assert.Zero(t, third.ExitCode)
+
+	now := time.Now()
+	third.StartedAt, third.FinishedAt = now, now
+	assert.Equal(t, StatusPassedAfterRetry, third.Status())
+	assert.True(t, third.Passed())
+}
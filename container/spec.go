@@ -0,0 +1,309 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/cron"
+	"github.com/samalba/dockerclient"
+)
+
+// TestSpec is the trigger configuration of a test container parsed from its labels.
+// Labels, that can not be parsed, are reported in Errors and the trigger is disabled.
+type TestSpec struct {
+	// Docker is the Docker events trigger, nil if test container is not subscribed to Docker events
+	Docker *DockerTrigger
+	// Debounce is the Docker events debounce window, nil if not set
+	Debounce *time.Duration
+	// Webhook is the inbound webhook events trigger, nil if test container is not subscribed to webhook events
+	Webhook *WebhookTrigger
+	// Timer is the recurrent run interval, nil if not set
+	Timer *time.Duration
+	// Cron is the run schedule, nil if not set
+	Cron *cron.Schedule
+	// Startup runs test container once when tugbot starts
+	Startup bool
+	// Discovered runs test container once when discovered by tugbot
+	Discovered bool
+	// Errors are label parse errors
+	Errors []error
+}
+
+// DockerTrigger is a Docker events trigger.
+type DockerTrigger struct {
+	// Filters are filter values by filter name (type, action, container, image, label)
+	Filters map[string]string
+	matcher eventMatcher
+}
+
+// WebhookTrigger is an inbound webhook events trigger.
+type WebhookTrigger struct {
+	// Filters are filter values by payload field name
+	Filters  map[string]string
+	matchers map[string]valueFilter
+}
+
+// Match returns whether or not Docker event e passes trigger filters.
+func (t DockerTrigger) Match(e *dockerclient.Event) bool {
+	return t.matcher.match(e)
+}
+
+// Reject returns the filter (label name) rejecting Docker event e, empty if event passes trigger filters.
+func (t DockerTrigger) Reject(e *dockerclient.Event) string {
+	return t.matcher.reject(e)
+}
+
+// Match returns whether or not all trigger filters match webhook event payload fields.
+func (t WebhookTrigger) Match(payload map[string]string) bool {
+	for field, filter := range t.matchers {
+		val, ok := payload[field]
+		if !ok || !filter.match(val) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newTestSpec parses test container trigger labels.
+func newTestSpec(labels map[string]string) *TestSpec {
+	ret := &TestSpec{}
+	if _, ok := labels[TugbotEventDocker]; ok {
+		trigger := &DockerTrigger{Filters: make(map[string]string)}
+		for _, filter := range []string{TypeFilter, ActionFilter, ContainerFilter, ImageFilter, LabelFilter} {
+			if val, ok := labels[filter]; ok {
+				trigger.Filters[strings.TrimPrefix(filter, TugbotEventDocker+"-filter-")] = val
+			}
+		}
+		var errs []error
+		trigger.matcher, errs = compileEventFilter(labels)
+		ret.Errors = append(ret.Errors, errs...)
+		ret.Docker = trigger
+	}
+	if val, ok := labels[TugbotEventDockerDebounce]; ok {
+		window, err := time.ParseDuration(val)
+		if err != nil {
+			ret.Errors = append(ret.Errors, labelError(TugbotEventDockerDebounce, val, err))
+		} else {
+			ret.Debounce = &window
+		}
+	}
+	if _, ok := labels[TugbotEventWebhook]; ok {
+		trigger := &WebhookTrigger{Filters: make(map[string]string), matchers: make(map[string]valueFilter)}
+		for label, val := range labels {
+			if strings.HasPrefix(label, WebhookFilterPrefix) {
+				field := strings.TrimPrefix(label, WebhookFilterPrefix)
+				filter, err := newValueFilter(val)
+				if err != nil {
+					ret.Errors = append(ret.Errors, labelError(label, val, err))
+				}
+				trigger.Filters[field] = val
+				trigger.matchers[field] = filter
+			}
+		}
+		ret.Webhook = trigger
+	}
+	if val, ok := labels[TugbotEventTimer]; ok {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			ret.Errors = append(ret.Errors, labelError(TugbotEventTimer, val, err))
+		} else {
+			ret.Timer = &interval
+		}
+	}
+	if val, ok := labels[TugbotEventCron]; ok {
+		schedule, err := cron.Parse(val)
+		if err != nil {
+			ret.Errors = append(ret.Errors, labelError(TugbotEventCron, val, err))
+		} else {
+			ret.Cron = schedule
+		}
+	}
+	_, ret.Startup = labels[TugbotEventStartup]
+	_, ret.Discovered = labels[TugbotEventDiscovered]
+
+	return ret
+}
+
+func labelError(label string, val string, err error) error {
+	return fmt.Errorf("invalid %s label: %s (%v)", label, val, err)
+}
+
+// valueFilter matches a value against a comma separated list of values or RE2 regexp ('re2:' prefix).
+type valueFilter struct {
+	list []string
+	re   *regexp.Regexp
+}
+
+// newValueFilter returns a new valueFilter, that matches nothing if filter is an invalid regexp.
+func newValueFilter(filter string) (valueFilter, error) {
+	if strings.HasPrefix(filter, re2Prefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(filter, re2Prefix))
+		if err != nil {
+			return valueFilter{list: []string{}}, err
+		}
+		return valueFilter{re: re}, nil
+	}
+
+	return valueFilter{list: splitAndTrimSpaces(filter, ",")}, nil
+}
+
+func (f valueFilter) match(val string) bool {
+	if f.re != nil {
+		return f.re.MatchString(val)
+	}
+
+	return sliceContains(val, f.list)
+}
+
+// eventMatcher is a compiled Docker event filter
+type eventMatcher struct {
+	types      []string
+	actions    []string
+	containers *valueFilter
+	images     *valueFilter
+	labels     [][]string
+}
+
+// compileEventFilter compiles Docker event filters f (by filter label), returning filter parse errors.
+func compileEventFilter(f map[string]string) (eventMatcher, []error) {
+	var ret eventMatcher
+	var errs []error
+	if typeFilter, ok := f[TypeFilter]; ok {
+		ret.types = splitAndTrimSpaces(typeFilter, ",")
+	}
+	if actionFilter, ok := f[ActionFilter]; ok {
+		ret.actions = splitAndTrimSpaces(actionFilter, ",")
+	}
+	for label, target := range map[string]**valueFilter{ContainerFilter: &ret.containers, ImageFilter: &ret.images} {
+		if val, ok := f[label]; ok {
+			filter, err := newValueFilter(val)
+			if err != nil {
+				errs = append(errs, labelError(label, val, err))
+			}
+			*target = &filter
+		}
+	}
+	if labelFilter, ok := f[LabelFilter]; ok {
+		for _, label := range splitAndTrimSpaces(labelFilter, ",") {
+			ret.labels = append(ret.labels, splitAndTrimSpaces(label, "="))
+		}
+	}
+
+	return ret, errs
+}
+
+func (m eventMatcher) match(e *dockerclient.Event) bool {
+	return m.reject(e) == ""
+}
+
+// reject returns the filter (label name) rejecting event e, empty if event passes all filters.
+func (m eventMatcher) reject(e *dockerclient.Event) string {
+	// filter by event type
+	if m.types != nil && !sliceContains(e.Type, m.types) {
+		return TypeFilter
+	}
+	// filter by event action
+	if m.actions != nil && !sliceContains(e.Action, m.actions) {
+		return ActionFilter
+	}
+	// filter by container name or name regexp
+	if m.containers != nil && !m.containers.match(e.Actor.Attributes["name"]) {
+		return ContainerFilter
+	}
+	// filter by event image
+	if m.images != nil {
+		// get image name from event.From field
+		imageName := e.From
+		// in case of "image" event.Type, event.ID contains image ID (name:tag) for 'pull' action and sha256:num for untag and delete
+		if e.Type == "image" {
+			imageName = e.ID
+		}
+		if !m.images.match(imageName) {
+			return ImageFilter
+		}
+	}
+	// filter by event labels
+	for _, label := range m.labels {
+		if !mapContains(e.Actor.Attributes, label) {
+			return LabelFilter
+		}
+	}
+
+	return ""
+}
+
+// specCache caches test specs by container ID, a spec is rebuilt when container labels change
+type specCache struct {
+	mu    sync.Mutex
+	specs map[string]cachedSpec
+}
+
+type cachedSpec struct {
+	labels map[string]string
+	spec   *TestSpec
+}
+
+var specs = &specCache{specs: make(map[string]cachedSpec)}
+
+// get returns cached test spec of container id with labels, parsing labels on cache miss.
+func (c *specCache) get(id string, name string, labels map[string]string) *TestSpec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.specs[id]; ok && equalLabels(cached.labels, labels) {
+		return cached.spec
+	}
+	spec := newTestSpec(labels)
+	for _, err := range spec.Errors {
+		log.Errorf("Test container %s misconfigured: %v", name, err)
+	}
+	c.specs[id] = cachedSpec{labels: copyLabels(labels), spec: spec}
+
+	return spec
+}
+
+// prune removes cached test specs of containers not in ids.
+func (c *specCache) prune(ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	for id := range c.specs {
+		if !keep[id] {
+			delete(c.specs, id)
+		}
+	}
+}
+
+// PruneSpecs drops cached test specs of all test containers except for ids.
+func PruneSpecs(ids []string) {
+	specs.prune(ids)
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	ret := make(map[string]string, len(labels))
+	for k, v := range labels {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+func equalLabels(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if val, ok := b[k]; !ok || val != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,83 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTestSpec(t *testing.T) {
+	spec := newTestSpec(map[string]string{
+		TugbotEventDocker:               "",
+		ActionFilter:                    "start",
+		ContainerFilter:                 "re2:^api",
+		TugbotEventDockerDebounce:       "10s",
+		TugbotEventWebhook:              "",
+		WebhookFilterPrefix + "service": "voting-app",
+		TugbotEventTimer:                "1m",
+		TugbotEventCron:                 "0 2 * * *",
+		TugbotEventStartup:              "",
+	})
+
+	assert.Empty(t, spec.Errors)
+	if assert.NotNil(t, spec.Docker) {
+		assert.Equal(t, map[string]string{"action": "start", "container": "re2:^api"}, spec.Docker.Filters)
+		assert.True(t, spec.Docker.Match(&dockerclient.Event{Action: "start", Actor: dockerclient.Actor{Attributes: map[string]string{"name": "api-1"}}}))
+		assert.False(t, spec.Docker.Match(&dockerclient.Event{Action: "start", Actor: dockerclient.Actor{Attributes: map[string]string{"name": "web-1"}}}))
+	}
+	if assert.NotNil(t, spec.Debounce) {
+		assert.Equal(t, 10*time.Second, *spec.Debounce)
+	}
+	if assert.NotNil(t, spec.Webhook) {
+		assert.Equal(t, map[string]string{"service": "voting-app"}, spec.Webhook.Filters)
+		assert.True(t, spec.Webhook.Match(map[string]string{"service": "voting-app"}))
+		assert.False(t, spec.Webhook.Match(map[string]string{"deploy.version": "1.3.0"}))
+	}
+	if assert.NotNil(t, spec.Timer) {
+		assert.Equal(t, time.Minute, *spec.Timer)
+	}
+	if assert.NotNil(t, spec.Cron) {
+		assert.Equal(t, "0 2 * * *", spec.Cron.String())
+	}
+	assert.True(t, spec.Startup)
+	assert.False(t, spec.Discovered)
+}
+
+func TestNewTestSpec_Errors(t *testing.T) {
+	spec := newTestSpec(map[string]string{
+		TugbotEventDocker:  "",
+		ImageFilter:        "re2:(tests",
+		TugbotEventTimer:   "often",
+		TugbotEventCron:    "never",
+		TugbotEventStartup: "",
+	})
+
+	assert.Len(t, spec.Errors, 3)
+	if assert.NotNil(t, spec.Docker) {
+		assert.False(t, spec.Docker.Match(&dockerclient.Event{From: "tests"}))
+	}
+	assert.Nil(t, spec.Timer)
+	assert.Nil(t, spec.Cron)
+	assert.True(t, spec.Startup)
+}
+
+func TestSpecCache(t *testing.T) {
+	cache := &specCache{specs: make(map[string]cachedSpec)}
+	labels := map[string]string{TugbotEventTimer: "1m"}
+
+	spec := cache.get("id", "tests", labels)
+	assert.True(t, spec == cache.get("id", "tests", map[string]string{TugbotEventTimer: "1m"}))
+
+	// labels changed, e.g. test container recreated with the same ID or config file reloaded
+	labels[TugbotEventTimer] = "2m"
+	updated := cache.get("id", "tests", labels)
+	assert.False(t, spec == updated)
+	assert.Equal(t, 2*time.Minute, *updated.Timer)
+
+	cache.get("other", "other-tests", labels)
+	cache.prune([]string{"other"})
+	assert.Len(t, cache.specs, 1)
+	assert.Contains(t, cache.specs, "other")
+}
@@ -1,7 +1,7 @@
 package container
 
 import (
-	"regexp"
+	"encoding/json"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -32,19 +32,11 @@ func sliceContains(val string, s []string) bool {
 }
 
 func inFilterOrList(val string, filter string) bool {
-	// check if filter is a RE2 regexp
-	if strings.HasPrefix(filter, re2Prefix) {
-		pattern := strings.Trim(filter, re2Prefix)
-		log.Debugf("Using RE2 pattern: '%s'", pattern)
-		var matched bool
-		var err error
-		if matched, err = regexp.MatchString(pattern, val); err != nil {
-			log.Error(err)
-		}
-		return matched
+	f, err := newValueFilter(filter)
+	if err != nil {
+		log.Error(err)
 	}
-	// check if value in list
-	return sliceContains(val, splitAndTrimSpaces(filter, ","))
+	return f.match(val)
 }
 
 func mapContains(m map[string]string, kv []string) bool {
@@ -59,3 +51,42 @@ func mapContains(m map[string]string, kv []string) bool {
 	}
 	return false
 }
+
+// parseList parses JSON array of strings or a string split by sep (whitespace when sep is empty).
+func parseList(val string, sep string) ([]string, error) {
+	val = strings.TrimSpace(val)
+	if strings.HasPrefix(val, "[") {
+		var ret []string
+		err := json.Unmarshal([]byte(val), &ret)
+		return ret, err
+	}
+	if sep == "" {
+		return strings.Fields(val), nil
+	}
+	ret := []string{}
+	for _, s := range splitAndTrimSpaces(val, sep) {
+		if s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret, nil
+}
+
+// mergeEnv merges lists of environment variables (KEY=value), a variable in a later
+// list overrides the same variable in earlier lists.
+func mergeEnv(lists ...[]string) []string {
+	index := make(map[string]int)
+	ret := []string{}
+	for _, list := range lists {
+		for _, env := range list {
+			key := strings.SplitN(env, "=", 2)[0]
+			if i, ok := index[key]; ok {
+				ret[i] = env
+			} else {
+				index[key] = len(ret)
+				ret = append(ret, env)
+			}
+		}
+	}
+	return ret
+}
@@ -38,6 +38,15 @@ func TestInFilterOrList_FilterFalse(t *testing.T) {
 	assert.False(t, inFilterOrList("cont123", "re2:^NO"))
 }
 
+func TestInFilterOrList_FilterSuffix(t *testing.T) {
+	assert.False(t, inFilterOrList("cont123", "re2:.*er"))
+	assert.True(t, inFilterOrList("tester", "re2:.*er"))
+}
+
+func TestInFilterOrList_InvalidFilter(t *testing.T) {
+	assert.False(t, inFilterOrList("cont123", "re2:(cont"))
+}
+
 func TestMapContains_True(t *testing.T) {
 	m := map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"}
 	assert.True(t, mapContains(m, []string{"k2", "v2"}))
@@ -52,3 +61,26 @@ func TestMapContains_False(t *testing.T) {
 	m := map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"}
 	assert.False(t, mapContains(m, []string{"x", "y"}))
 }
+
+func TestParseList(t *testing.T) {
+	args, err := parseList(`["sh", "-c", "go test ./..."]`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", "go test ./..."}, args)
+
+	args, err = parseList("java -jar  mytests.jar", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"java", "-jar", "mytests.jar"}, args)
+
+	args, err = parseList("A=1, B=2,", ",")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "B=2"}, args)
+
+	_, err = parseList(`["sh", `, "")
+	assert.Error(t, err)
+}
+
+func TestMergeEnv(t *testing.T) {
+	assert.Equal(t,
+		[]string{"PATH=/usr/bin", "A=2", "B=1", "C=3"},
+		mergeEnv([]string{"PATH=/bin", "A=1", "B=1"}, []string{"A=2", "PATH=/usr/bin"}, []string{"C=3"}))
+}
@@ -0,0 +1,202 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// labelNames are all tugbot labels, other labels with 'tugbot-' prefix are unknown
+var labelNames = []string{
+	TugbotService, TugbotTest, TugbotCreatedFrom,
+	TugbotEventDocker, TypeFilter, ActionFilter, ContainerFilter, ImageFilter, LabelFilter, TugbotEventDockerDebounce,
+	TugbotEventWebhook, TugbotEventTimer, TugbotEventCron, TugbotEventStartup, TugbotEventDiscovered,
+	TugbotResultsDir, TugbotResultsFormat, TugbotConcurrency, TugbotKeepLast, TugbotKeepFor,
+	TugbotRunCmd, TugbotRunEntrypoint, TugbotRunEnv, TugbotRunWorkdir,
+	TugbotTimeout, TugbotRetryCount, TugbotRetryBackoff, TugbotPriority,
+}
+
+// eventActions are Docker event actions by Docker event type
+var eventActions = map[string][]string{
+	"container": {"attach", "commit", "copy", "create", "destroy", "detach", "die", "exec_create", "exec_detach", "exec_start",
+		"export", "health_status", "kill", "oom", "pause", "rename", "resize", "restart", "start", "stop", "top", "unpause", "update"},
+	"image":   {"delete", "import", "load", "pull", "push", "save", "tag", "untag"},
+	"plugin":  {"install", "enable", "disable", "remove"},
+	"volume":  {"create", "mount", "unmount", "destroy"},
+	"network": {"create", "connect", "disconnect", "destroy"},
+	"daemon":  {"reload"},
+	// swarm mode event types
+	"service": {"create", "remove", "update"},
+	"node":    {"create", "remove", "update"},
+	"secret":  {"create", "remove", "update"},
+	"config":  {"create", "remove", "update"},
+}
+
+// Validate returns test container misconfiguration, see ValidateLabels.
+func (c Container) Validate() []error {
+	return ValidateLabels(c.containerInfo.Config.Labels)
+}
+
+// ValidateLabels returns test container label errors: unknown tugbot labels, label values
+// that can not be parsed, filters without trigger label and Docker event type and action
+// filters that never match an event.
+func ValidateLabels(labels map[string]string) []error {
+	var ret []error
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	for _, label := range names {
+		if err := validateLabel(label, labels); err != nil {
+			ret = append(ret, err)
+		}
+	}
+	ret = append(ret, newTestSpec(labels).Errors...)
+
+	return append(ret, validateEventFilter(labels)...)
+}
+
+// validateLabel returns an error if label is unknown, requires another label or its
+// value (of a label not parsed into TestSpec) can not be parsed.
+func validateLabel(label string, labels map[string]string) error {
+	val := labels[label]
+	if strings.HasPrefix(label, WebhookFilterPrefix) {
+		return requireLabel(label, TugbotEventWebhook, labels)
+	}
+	if !strings.HasPrefix(label, "tugbot-") {
+		return nil
+	}
+	if !sliceContains(label, labelNames) {
+		if known := similarLabel(label); known != "" {
+			return fmt.Errorf("unknown label %s, did you mean %s?", label, known)
+		}
+		return fmt.Errorf("unknown label %s", label)
+	}
+	var err error
+	switch label {
+	case TypeFilter, ActionFilter, ContainerFilter, ImageFilter, LabelFilter, TugbotEventDockerDebounce:
+		err = requireLabel(label, TugbotEventDocker, labels)
+	case TugbotConcurrency:
+		if !sliceContains(val, []string{ConcurrencySkip, ConcurrencyQueue, ConcurrencyReplace}) {
+			err = fmt.Errorf("invalid %s label: %s, expected one of: %s, %s, %s", label, val, ConcurrencySkip, ConcurrencyQueue, ConcurrencyReplace)
+		}
+	case TugbotKeepLast, TugbotRetryCount:
+		if n, e := strconv.Atoi(val); e != nil || n < 0 {
+			err = fmt.Errorf("invalid %s label: %s, non negative integer is expected", label, val)
+		}
+	case TugbotPriority:
+		if _, e := strconv.Atoi(val); e != nil {
+			err = labelError(label, val, e)
+		}
+	case TugbotKeepFor, TugbotTimeout, TugbotRetryBackoff:
+		if d, e := time.ParseDuration(val); e != nil {
+			err = labelError(label, val, e)
+		} else if d < 0 {
+			err = fmt.Errorf("invalid %s label: %s, non negative duration is expected", label, val)
+		}
+	case TugbotRunCmd, TugbotRunEntrypoint:
+		if _, e := parseList(val, ""); e != nil {
+			err = labelError(label, val, e)
+		}
+	case TugbotRunEnv:
+		env, e := parseList(val, ",")
+		for _, curr := range env {
+			if e == nil && !strings.Contains(curr, "=") {
+				e = fmt.Errorf("missing '=' in %s", curr)
+			}
+		}
+		if e != nil {
+			err = labelError(label, val, e)
+		}
+	}
+
+	return err
+}
+
+func requireLabel(label string, required string, labels map[string]string) error {
+	if _, ok := labels[required]; !ok {
+		return fmt.Errorf("label %s has no effect without %s label", label, required)
+	}
+
+	return nil
+}
+
+// validateEventFilter returns errors of Docker event type and action filters, that never match an event.
+func validateEventFilter(labels map[string]string) []error {
+	var ret []error
+	var types []string
+	if val, ok := labels[TypeFilter]; ok {
+		for _, t := range splitAndTrimSpaces(val, ",") {
+			if _, ok := eventActions[t]; !ok {
+				ret = append(ret, fmt.Errorf("invalid %s label: unknown Docker event type %s", TypeFilter, t))
+				continue
+			}
+			types = append(types, t)
+		}
+	} else {
+		for t := range eventActions {
+			types = append(types, t)
+		}
+	}
+	if val, ok := labels[ActionFilter]; ok && len(types) > 0 {
+		for _, action := range splitAndTrimSpaces(val, ",") {
+			valid := false
+			for _, t := range types {
+				valid = valid || sliceContains(action, eventActions[t])
+			}
+			if !valid && len(types) == len(eventActions) {
+				ret = append(ret, fmt.Errorf("invalid %s label: unknown Docker event action %s", ActionFilter, action))
+			} else if !valid {
+				ret = append(ret, fmt.Errorf("invalid %s label: Docker event action %s never occurs for event type %s",
+					ActionFilter, action, strings.Join(types, ", ")))
+			}
+		}
+	}
+
+	return ret
+}
+
+// similarLabel returns known label within edit distance of 2 from label, empty if there is no such label.
+func similarLabel(label string) string {
+	ret, min := "", 3
+	for _, known := range labelNames {
+		if d := distance(label, known); d < min {
+			ret, min = known, d
+		}
+	}
+
+	return ret
+}
+
+// distance returns Levenshtein distance between a and b.
+func distance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,115 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func validate(labels map[string]string) []string {
+	c := Container{containerInfo: &dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{Labels: labels}}}
+	ret := []string{}
+	for _, err := range c.Validate() {
+		ret = append(ret, err.Error())
+	}
+
+	return ret
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, validate(map[string]string{
+		TugbotTest:                      "true",
+		TugbotEventDocker:               "",
+		TypeFilter:                      "container,image",
+		ActionFilter:                    "start,pull",
+		ContainerFilter:                 "re2:^api",
+		TugbotEventDockerDebounce:       "10s",
+		TugbotEventWebhook:              "",
+		WebhookFilterPrefix + "service": "api",
+		TugbotEventTimer:                "1m",
+		TugbotConcurrency:               ConcurrencyQueue,
+		TugbotKeepLast:                  "5",
+		TugbotTimeout:                   "10m",
+		TugbotRetryCount:                "2",
+		TugbotPriority:                  "-1",
+		TugbotRunCmd:                    `["go", "test"]`,
+		TugbotRunEnv:                    "DEBUG=1,ENV=ci",
+		"maintainer":                    "qa",
+	}))
+}
+
+func TestValidate_UnknownLabel(t *testing.T) {
+	assert.Equal(t, []string{
+		"unknown label tugbot-event-docker-filter-actoin, did you mean tugbot-event-docker-filter-action?",
+		"unknown label tugbot-notify",
+	}, validate(map[string]string{
+		TugbotTest:                          "true",
+		TugbotEventDocker:                   "",
+		"tugbot-event-docker-filter-actoin": "start",
+		"tugbot-notify":                     "slack",
+	}))
+}
+
+func TestValidate_InvalidValues(t *testing.T) {
+	assert.Equal(t, []string{
+		"invalid tugbot-concurrency label: sometimes, expected one of: skip, queue, replace",
+		"invalid tugbot-keep-last label: -1, non negative integer is expected",
+		`invalid tugbot-run-env label: DEBUG (missing '=' in DEBUG)`,
+		`invalid tugbot-event-docker-filter-image label: re2:(tests (error parsing regexp: missing closing ): ` + "`(tests`)",
+		`invalid tugbot-event-timer label: often (time: invalid duration "often")`,
+	}, validate(map[string]string{
+		TugbotTest:        "true",
+		TugbotEventDocker: "",
+		ImageFilter:       "re2:(tests",
+		TugbotEventTimer:  "often",
+		TugbotConcurrency: "sometimes",
+		TugbotKeepLast:    "-1",
+		TugbotRunEnv:      "DEBUG",
+	}))
+}
+
+func TestValidate_FilterWithoutTrigger(t *testing.T) {
+	assert.Equal(t, []string{
+		"label tugbot-event-docker-filter-type has no effect without tugbot-event-docker label",
+		"label tugbot-event-webhook-filter-service has no effect without tugbot-event-webhook label",
+	}, validate(map[string]string{
+		TugbotTest:                      "true",
+		TypeFilter:                      "container",
+		WebhookFilterPrefix + "service": "api",
+	}))
+}
+
+func TestValidate_EventTypeAction(t *testing.T) {
+	assert.Equal(t, []string{
+		"invalid tugbot-event-docker-filter-type label: unknown Docker event type task",
+		"invalid tugbot-event-docker-filter-action label: Docker event action pull never occurs for event type container",
+	}, validate(map[string]string{
+		TugbotTest:        "true",
+		TugbotEventDocker: "",
+		TypeFilter:        "container,task",
+		ActionFilter:      "start,pull",
+	}))
+	assert.Equal(t, []string{
+		"invalid tugbot-event-docker-filter-action label: unknown Docker event action started",
+	}, validate(map[string]string{
+		TugbotTest:        "true",
+		TugbotEventDocker: "",
+		ActionFilter:      "started",
+	}))
+	// swarm mode event types
+	assert.Empty(t, validate(map[string]string{
+		TugbotTest:        "true",
+		TugbotEventDocker: "",
+		TypeFilter:        "service,node,secret,config",
+		ActionFilter:      "create,remove,update",
+	}))
+	assert.Equal(t, []string{
+		"invalid tugbot-event-docker-filter-action label: Docker event action start never occurs for event type service",
+	}, validate(map[string]string{
+		TugbotTest:        "true",
+		TugbotEventDocker: "",
+		TypeFilter:        "service",
+		ActionFilter:      "start",
+	}))
+}
@@ -0,0 +1,55 @@
+// Package cron parses cron expressions and calculates schedule activation times,
+// using github.com/robfig/cron parser.
+//
+// Supported syntax is the standard 5 fields cron expression (minute, hour, day of month,
+// month, day of week) or 6 fields expression with leading seconds field. Each field accepts
+// '*', '?', values, ranges ('1-5'), lists ('1,15') and steps ('*/15', '0-30/10'); month and
+// day of week fields accept names ('JAN', 'MON'). The expression can be prefixed with a time
+// zone ('CRON_TZ=Europe/London 0 2 * * MON-FRI'), local time zone is used otherwise.
+// Descriptors '@yearly', '@monthly', '@weekly', '@daily', '@hourly' and '@every <duration>'
+// are also supported.
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	robfig "github.com/robfig/cron/v3"
+)
+
+var parser = robfig.NewParser(robfig.SecondOptional | robfig.Minute | robfig.Hour |
+	robfig.Dom | robfig.Month | robfig.Dow | robfig.Descriptor)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	spec     string
+	schedule robfig.Schedule
+}
+
+// Parse parses cron expression spec.
+func Parse(spec string) (ret *Schedule, err error) {
+	spec = strings.TrimSpace(spec)
+	defer func() {
+		// parser panics on a time zone without fields, e.g. 'CRON_TZ=UTC'
+		if r := recover(); r != nil {
+			ret, err = nil, fmt.Errorf("invalid cron expression: %s", spec)
+		}
+	}()
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schedule{spec: spec, schedule: schedule}, nil
+}
+
+// String returns the cron expression.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the next activation time after t, zero time if schedule never activates.
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2016, time.October, 12, 10, 20, 30, 500, time.UTC) // Wednesday
+	for _, test := range []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2016, time.October, 12, 10, 21, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2016, time.October, 12, 10, 20, 40, 0, time.UTC)},
+		{"15 * * * *", time.Date(2016, time.October, 12, 11, 15, 0, 0, time.UTC)},
+		{"0 2 * * MON-FRI", time.Date(2016, time.October, 13, 2, 0, 0, 0, time.UTC)},
+		{"0 2 * * sat,sun", time.Date(2016, time.October, 15, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2016, time.October, 16, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2016, time.October, 12, 11, 20, 30, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 10-12/2 * * *", time.Date(2016, time.October, 12, 10, 30, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 0 1 * FRI", time.Date(2016, time.October, 14, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2016, time.October, 12, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2016, time.October, 16, 0, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=UTC 0 12 * * *", time.Date(2016, time.October, 12, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		s, err := Parse(test.spec)
+		if assert.NoError(t, err, test.spec) {
+			next := s.Next(from)
+			assert.True(t, test.next.Equal(next), "%s: expected %s, actual %s", test.spec, test.next, next)
+		}
+	}
+}
+
+func TestScheduleNext_TimeZone(t *testing.T) {
+	s, err := Parse("TZ=America/New_York 0 2 * * *")
+	assert.NoError(t, err)
+	next := s.Next(time.Date(2016, time.October, 12, 0, 0, 0, 0, time.UTC))
+	assert.True(t, time.Date(2016, time.October, 12, 6, 0, 0, 0, time.UTC).Equal(next), next.String())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+		"CRON_TZ=Nowhere/Never * * * * *",
+		"CRON_TZ=UTC",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -3,9 +3,11 @@ package main // import "github.com/gaia-docker/tugbot"
 import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
-	"github.com/gaia-docker/tugbot-common"
 	"github.com/gaia-docker/tugbot/actions"
+	"github.com/gaia-docker/tugbot/api"
 	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/results"
+	"github.com/gaia-docker/tugbot/webhooks"
 	"github.com/samalba/dockerclient"
 
 	"crypto/tls"
@@ -23,8 +25,14 @@ import (
 
 var (
 	client       container.Client
+	definitions  container.TestDefinitions
+	configFile   string
+	runner       *actions.Runner
+	apiServer    *api.Server
 	names        []string
-	publisher    common.Publisher
+	dryRun       bool
+	publisher    *webhooks.Publisher
+	eventFilter  container.EventFilter
 	wgr          sync.WaitGroup
 	wgp          sync.WaitGroup
 	wgt          sync.WaitGroup
@@ -53,6 +61,20 @@ func main() {
 	app.ArgsUsage = "test containers: name, list of names, or none (for all test containers)"
 	app.Before = before
 	app.Action = start
+	app.Commands = []cli.Command{
+		{
+			Name:      "validate",
+			Usage:     "validate labels of test containers and exit; exit code is 1 if a test container is misconfigured",
+			ArgsUsage: "test containers: name, list of names, or none (for all test containers)",
+			Action:    validate,
+		},
+		{
+			Name:      "explain",
+			Usage:     "explain which test containers a Docker event would trigger and which filter rejected it, without running them",
+			ArgsUsage: "event: JSON, file of events recorded by 'docker events --format \"{{json .}}\"' or '-' for stdin; then test containers: name, list of names, or none (for all test containers)",
+			Action:    explain,
+		},
+	}
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:   "host, H",
@@ -84,6 +106,21 @@ func main() {
 			Usage: "client key for TLS authentication",
 			Value: fmt.Sprintf("%s/key.pem", rootCertPath),
 		},
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "YAML or JSON configuration file declaring test containers; reloaded on SIGHUP",
+			Value:  "",
+			EnvVar: "TUGBOT_CONFIG",
+		},
+		cli.StringSliceFlag{
+			Name:  "container, c",
+			Usage: "specify/overwrite test settings of a test container, semicolon separated list of test labels; for example: 'name=selenium_tests;results=/var/log/results;event.docker=create'",
+		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "log test container runs triggered by events, timers and API instead of running test containers",
+			EnvVar: "TUGBOT_DRY_RUN",
+		},
 		cli.BoolFlag{
 			Name:  "debug",
 			Usage: "enable debug mode with verbose logging",
@@ -94,6 +131,93 @@ func main() {
 			Value:  "",
 			EnvVar: "TUGBOT_WEBHOOKS",
 		},
+		cli.StringFlag{
+			Name:   "webhooks-config",
+			Usage:  "JSON file configuring webhooks with headers, authentication, TLS and payload signing secret; see README",
+			Value:  "",
+			EnvVar: "TUGBOT_WEBHOOKS_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-filter-type",
+			Usage:  "publish to webhooks only Docker events of these types, list separated by ','; for example: 'container,image'",
+			EnvVar: "TUGBOT_WEBHOOKS_FILTER_TYPE",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-filter-action",
+			Usage:  "publish to webhooks only Docker events with these actions, list separated by ','; for example: 'start,die'",
+			EnvVar: "TUGBOT_WEBHOOKS_FILTER_ACTION",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-filter-container",
+			Usage:  "publish to webhooks only Docker events of these containers, list of names separated by ',' or name regexp prefixed by 're2:'",
+			EnvVar: "TUGBOT_WEBHOOKS_FILTER_CONTAINER",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-filter-image",
+			Usage:  "publish to webhooks only Docker events of these images, list of names separated by ',' or name regexp prefixed by 're2:'",
+			EnvVar: "TUGBOT_WEBHOOKS_FILTER_IMAGE",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-filter-label",
+			Usage:  "publish to webhooks only Docker events with these labels, list of 'key' or 'key=value' separated by ','",
+			EnvVar: "TUGBOT_WEBHOOKS_FILTER_LABEL",
+		},
+		cli.StringFlag{
+			Name:   "webhooks-spool-dir",
+			Usage:  "directory keeping undelivered webhook events across restarts; events are kept in memory only when empty",
+			Value:  "",
+			EnvVar: "TUGBOT_WEBHOOKS_SPOOL_DIR",
+		},
+		cli.IntFlag{
+			Name:   "webhooks-spool-size",
+			Usage:  "max number of undelivered events kept per webhook, the oldest events are dropped",
+			Value:  webhooks.DefaultSpoolSize,
+			EnvVar: "TUGBOT_WEBHOOKS_SPOOL_SIZE",
+		},
+		cli.DurationFlag{
+			Name:   "webhooks-max-backoff",
+			Usage:  "max delay between webhook delivery retries, the delay starts at 1s and doubles on each failure",
+			Value:  webhooks.DefaultMaxBackoff,
+			EnvVar: "TUGBOT_WEBHOOKS_MAX_BACKOFF",
+		},
+		cli.StringFlag{
+			Name:   "result-service, r",
+			Usage:  "Result Service url for uploading test results",
+			Value:  "",
+			EnvVar: "TUGBOT_RESULT_SERVICE",
+		},
+		cli.IntFlag{
+			Name:   "keep-last",
+			Usage:  "number of containers created by tugbot to keep per test container; 0 for no limit",
+			EnvVar: "TUGBOT_KEEP_LAST",
+		},
+		cli.DurationFlag{
+			Name:   "keep-for",
+			Usage:  "remove containers created by tugbot after this duration; 0 for no limit",
+			EnvVar: "TUGBOT_KEEP_FOR",
+		},
+		cli.IntFlag{
+			Name:   "max-concurrent-tests",
+			Usage:  "max number of test containers running at once, runs exceeding it are queued by priority; 0 for no limit",
+			EnvVar: "TUGBOT_MAX_CONCURRENT_TESTS",
+		},
+		cli.DurationFlag{
+			Name:   "timeout",
+			Usage:  "default test container run timeout, test container is stopped (then killed) after it; 0 for no timeout",
+			EnvVar: "TUGBOT_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:   "api-addr",
+			Usage:  "address (host:port) to serve tugbot HTTP API on, for example: ':8080'; API is disabled when empty",
+			Value:  "",
+			EnvVar: "TUGBOT_API_ADDR",
+		},
+		cli.StringFlag{
+			Name:   "webhook-token",
+			Usage:  "token authenticating tugbot HTTP API requests triggering test container runs; webhook trigger is disabled when empty",
+			Value:  "",
+			EnvVar: "TUGBOT_WEBHOOK_TOKEN",
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -111,7 +235,34 @@ func before(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	client = container.NewClient(c.GlobalString("host"), tls, !c.GlobalBool("no-pull"))
+	configFile = c.GlobalString("config")
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	var overrides []container.Override
+	for _, val := range c.GlobalStringSlice("container") {
+		override, err := container.ParseOverride(val)
+		if err != nil {
+			return err
+		}
+		log.Infof("Overriding test settings of %s: %v", override.Name, override.Labels)
+		overrides = append(overrides, override)
+	}
+	definitions.SetOverrides(overrides)
+	client = container.NewClient(c.GlobalString("host"), tls, !c.GlobalBool("no-pull"), &definitions)
+	var handlers []actions.ResultHandler
+	if resultService := c.GlobalString("result-service"); resultService != "" {
+		handlers = append(handlers, results.NewCollector(client, resultService).Collect)
+	}
+	dryRun = c.GlobalBool("dry-run")
+	retention := actions.NewRetention(client, c.GlobalInt("keep-last"), c.GlobalDuration("keep-for"))
+	handlers = append(handlers, retention.Cleanup)
+	runner = actions.NewRunner(client, actions.RunnerConfig{
+		MaxConcurrent: c.GlobalInt("max-concurrent-tests"),
+		Timeout:       c.GlobalDuration("timeout"),
+		Events:        publishRunEvent,
+		DryRun:        dryRun,
+	}, handlers...)
 
 	return nil
 }
@@ -119,54 +270,272 @@ func before(c *cli.Context) error {
 func start(c *cli.Context) {
 	names = c.Args()
 	startMonitorEvents(c)
+	checkTestContainers()
 	startTicker()
-	log.Infof("Tugbot Started. Debug: %v, Webhooks: %v", c.GlobalBool("debug"), c.GlobalBool("webhooks"))
+	startAPI(c)
+	log.Infof("Tugbot Started. Debug: %v, Dry Run: %v, Webhooks: %v, Result Service: %s, Max Concurrent Tests: %d",
+		c.GlobalBool("debug"), dryRun, publisher != nil, c.GlobalString("result-service"), c.GlobalInt("max-concurrent-tests"))
 	waitForInterrupt()
 }
 
 func startMonitorEvents(c *cli.Context) {
 	client.StartMonitorEvents(runTestContainers)
-	webhooks := c.GlobalString("webhooks")
-	if webhooks != "" {
-		publisher = common.NewPublisher(strings.Split(webhooks, ";"))
+	endpoints, err := webhookEndpoints(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(endpoints) > 0 {
+		publisher, err = webhooks.NewPublisher(endpoints, webhooks.Config{
+			SpoolDir:   c.GlobalString("webhooks-spool-dir"),
+			SpoolSize:  c.GlobalInt("webhooks-spool-size"),
+			MaxBackoff: c.GlobalDuration("webhooks-max-backoff"),
+		})
+		if err != nil {
+			log.Fatalf("Failed to start publishing events to webhooks (%v)", err)
+		}
+		eventFilter = webhooksEventFilter(c)
 		client.StartMonitorEvents(publishEvent)
 	}
 }
 
+// checkTestContainers logs misconfigured test containers and publishes a warning event for each one.
+func checkTestContainers() {
+	validations, err := actions.Validate(client, names)
+	if err != nil {
+		log.Errorf("Failed to validate test containers (%v)", err)
+		return
+	}
+	for _, v := range validations {
+		if len(v.Errors) > 0 {
+			for _, err := range v.Errors {
+				log.Warnf("Test container %s misconfigured: %v", v.Container.Name(), err)
+			}
+			publishRunEvent(container.NewMisconfiguredEvent(v.Container, v.Errors))
+		}
+	}
+}
+
+// validate prints test containers misconfiguration, exits with code 1 if a test container is misconfigured.
+func validate(c *cli.Context) {
+	validations, err := actions.Validate(client, c.Args())
+	if err != nil {
+		log.Fatalf("Failed to validate test containers (%v)", err)
+	}
+	misconfigured := 0
+	for _, v := range validations {
+		if len(v.Errors) == 0 {
+			fmt.Printf("%s: OK\n", v.Container.Name())
+			continue
+		}
+		misconfigured++
+		fmt.Printf("%s:\n", v.Container.Name())
+		for _, err := range v.Errors {
+			fmt.Printf("  - %v\n", err)
+		}
+	}
+	fmt.Printf("Test containers: %d, misconfigured: %d\n", len(validations), misconfigured)
+	if misconfigured > 0 {
+		os.Exit(1)
+	}
+}
+
+// webhookEndpoints returns webhooks set by 'webhooks' option (urls) and 'webhooks-config' option (configuration file).
+func webhookEndpoints(c *cli.Context) ([]webhooks.Endpoint, error) {
+	var ret []webhooks.Endpoint
+	if urls := c.GlobalString("webhooks"); urls != "" {
+		ret = webhooks.NewEndpoints(strings.Split(urls, ";"))
+	}
+	if file := c.GlobalString("webhooks-config"); file != "" {
+		endpoints, err := webhooks.LoadEndpoints(file)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, endpoints...)
+	}
+
+	return ret, nil
+}
+
 func startTicker() {
 	wgt.Add(1)
 	var ctx context.Context
 	ctx, tickerCancel = context.WithCancel(context.Background())
 	go func() {
-		actions.RunTickerTestContainers(ctx, client, time.Second*18)
+		actions.RunTickerTestContainers(ctx, runner, time.Second*18)
 		wgt.Done()
 	}()
 }
+
+func startAPI(c *cli.Context) {
+	addr := c.GlobalString("api-addr")
+	if addr != "" {
+		apiServer = api.NewServer(client, runner, publisher, c.GlobalString("webhook-token"))
+		if err := apiServer.Start(addr); err != nil {
+			log.Fatalf("Failed to start API server on %s (%v)", addr, err)
+		}
+		log.Infof("API server listening on %s", addr)
+		if c.GlobalString("webhook-token") == "" {
+			log.Warn("API requests triggering test container runs are not authenticated, use --webhook-token to require a token")
+		}
+	}
+}
+
 func runTestContainers(e *dockerclient.Event, ec chan error, args ...interface{}) {
 	log.Debugf("Looking for test containers that should run on event: %+v", e)
 	wgr.Add(1)
-	if err := actions.Run(client, names, e); err != nil {
+	if dryRun {
+		explainEvent(e)
+	}
+	if err := actions.Run(runner, names, e); err != nil {
 		log.Error(err)
 	}
 	wgr.Done()
 }
 
+// explainEvent logs test containers event e triggers (runner only logs their runs in dry run mode)
+// and filters rejecting it.
+func explainEvent(e *dockerclient.Event) {
+	explanations, err := actions.Explain(client, names, e)
+	if err != nil {
+		log.Errorf("Failed to explain event (%v)", err)
+		return
+	}
+	for _, explanation := range explanations {
+		if explanation.Match {
+			log.Infof("Dry run: %s %s event triggers %s", e.Type, e.Action, explanation.Container.Name())
+		} else {
+			log.Debugf("Dry run: %s %s event does not trigger %s, %s", e.Type, e.Action, explanation.Container.Name(), explanation.Reason)
+		}
+	}
+}
+
+// explain prints which test containers events, passed as the first argument, would trigger.
+func explain(c *cli.Context) {
+	if !c.Args().Present() {
+		log.Fatal("Missing event: JSON, file or '-' for stdin")
+	}
+	events, err := readEvents(c.Args().First())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range events {
+		fmt.Printf("Event: %s %s (ID: %s, From: %s, Attributes: %v)\n", e.Type, e.Action, e.ID, e.From, e.Actor.Attributes)
+		explanations, err := actions.Explain(client, c.Args().Tail(), e)
+		if err != nil {
+			log.Fatalf("Failed to explain event (%v)", err)
+		}
+		for _, explanation := range explanations {
+			if explanation.Match {
+				fmt.Printf("  %s: triggered\n", explanation.Container.Name())
+			} else {
+				fmt.Printf("  %s: not triggered, %s\n", explanation.Container.Name(), explanation.Reason)
+			}
+		}
+	}
+}
+
+// readEvents reads Docker events from JSON argument, file or stdin ('-').
+func readEvents(arg string) ([]*dockerclient.Event, error) {
+	if trimmed := strings.TrimSpace(arg); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return container.ReadEvents(strings.NewReader(trimmed))
+	}
+	if arg == "-" {
+		return container.ReadEvents(os.Stdin)
+	}
+	f, err := os.Open(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return container.ReadEvents(f)
+}
+
+// webhooksEventFilter returns Docker event filter set by 'webhooks-filter-*' options.
+func webhooksEventFilter(c *cli.Context) container.EventFilter {
+	filters := make(map[string]string)
+	for flag, filter := range map[string]string{
+		"webhooks-filter-type":      container.TypeFilter,
+		"webhooks-filter-action":    container.ActionFilter,
+		"webhooks-filter-container": container.ContainerFilter,
+		"webhooks-filter-image":     container.ImageFilter,
+		"webhooks-filter-label":     container.LabelFilter,
+	} {
+		if val := c.GlobalString(flag); val != "" {
+			filters[filter] = val
+		}
+	}
+	ret, err := container.NewEventFilter(filters)
+	if err != nil {
+		log.Fatalf("Invalid webhooks filter (%v)", err)
+	}
+	if len(filters) > 0 {
+		log.Infof("Publishing to webhooks only Docker events matching: %v", filters)
+	}
+
+	return ret
+}
+
 func publishEvent(e *dockerclient.Event, ec chan error, args ...interface{}) {
+	if !eventFilter.Match(e) {
+		log.Debugf("Event filtered out, not publishing: %+v", e)
+		return
+	}
 	log.Debugf("Publishing event: %+v", e)
 	wgp.Add(1)
 	publisher.Publish(e)
 	wgp.Done()
 }
 
+func publishRunEvent(e *dockerclient.Event) {
+	if publisher != nil {
+		log.Debugf("Publishing test container run event: %+v", e)
+		publisher.Publish(e)
+	}
+}
+
+// loadConfig loads test definitions from configuration file, if set.
+func loadConfig() error {
+	if configFile == "" {
+		return nil
+	}
+	config, err := container.LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	definitions.Set(config.Tests)
+	log.Infof("Loaded configuration file %s (Tests: %d)", configFile, len(config.Tests))
+
+	return nil
+}
+
 func waitForInterrupt() {
-	// Graceful shut-down on SIGINT/SIGTERM/SIGQUIT
+	// Graceful shut-down on SIGINT/SIGTERM/SIGQUIT, reload configuration file on SIGHUP
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT)
-	<-c
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGHUP)
+	for sig := range c {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		log.Info("Reloading configuration file...")
+		if err := loadConfig(); err != nil {
+			log.Errorf("Failed to reload configuration file, keeping previous configuration (%v)", err)
+		}
+	}
+	if apiServer != nil {
+		log.Info("Stoping API server...")
+		apiServer.Stop()
+	}
 	wgr.Wait()
 	log.Info("Stoping monitor events...")
 	client.StopAllMonitorEvents()
 	wgp.Wait()
+	log.Info("Stoping debounced runs...")
+	runner.Stop()
+	if publisher != nil {
+		log.Info("Stoping webhooks delivery...")
+		publisher.Stop()
+	}
 	log.Info("Stoping ticker...")
 	tickerCancel()
 	wgt.Wait()
@@ -0,0 +1,143 @@
+package results
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gaia-docker/tugbot/container"
+)
+
+// Result Service upload query params
+const (
+	ParamImageName = "docker.imagename"
+	ParamExitCode  = "exitcode"
+	ParamStartTime = "start-time"
+	ParamEndTime   = "end-time"
+	ParamStatus    = "status"
+	ParamAttempt   = "attempt"
+	ParamFinal     = "final"
+	ParamRunID     = "run-id"
+)
+
+// Collector collects test results from exited test containers and uploads
+// them to the Result Service.
+type Collector struct {
+	client     container.Client
+	url        string
+	httpClient *http.Client
+}
+
+// NewCollector returns a new Collector, that uploads test results to Result Service url.
+func NewCollector(client container.Client, url string) *Collector {
+	return &Collector{
+		client:     client,
+		url:        url,
+		httpClient: &http.Client{Timeout: time.Minute * 5},
+	}
+}
+
+// Collect copies the test results directory out of the exited test container and
+// uploads it as a gzip archive. Collect can be used as an actions.ResultHandler.
+func (c *Collector) Collect(run *container.RunResult) {
+	if run.ContainerID == "" {
+		return
+	}
+	if err := c.collect(run); err != nil {
+		log.Errorf("Failed to collect test results of %s (%s) (%v)", run.ContainerName, run.ContainerID, err)
+	}
+}
+
+func (c *Collector) collect(run *container.RunResult) error {
+	tc, err := c.client.Inspect(run.ContainerID)
+	if err != nil {
+		return err
+	}
+	dir := tc.ResultsDir()
+	log.Debugf("Collecting test results from %s:%s", run.ContainerName, dir)
+	archive, err := c.client.CopyFromContainer(run.ContainerID, dir)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	if format := tc.ResultsFormat(); format != "" {
+		if IsSupportedFormat(format) {
+			return c.uploadTestSet(run, tc, format, archive)
+		}
+		log.Warnf("Unsupported test results format '%s' (%s), uploading results as gzip archive", format, run.ContainerName)
+	}
+
+	return c.upload(run, "application/gzip", gzipReader(archive))
+}
+
+func (c *Collector) uploadTestSet(run *container.RunResult, tc *container.Container, format string, archive io.Reader) error {
+	set, err := Parse(format, archive)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(Result{
+		RunID:       run.ID,
+		ImageName:   run.ImageName,
+		ContainerID: run.ContainerID,
+		StartedAt:   run.StartedAt,
+		FinishedAt:  run.FinishedAt,
+		ExitCode:    run.ExitCode,
+		HostName:    tc.Hostname(),
+		TestSet:     set,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.upload(run, "application/json", bytes.NewReader(body))
+}
+
+func (c *Collector) upload(run *container.RunResult, contentType string, body io.Reader) error {
+	resp, err := c.httpClient.Post(c.uploadURL(run), contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Result Service responded with %s", resp.Status)
+	}
+	log.Infof("Test results of %s (%s) uploaded to %s", run.ContainerName, run.ContainerID, c.url)
+
+	return nil
+}
+
+func (c *Collector) uploadURL(run *container.RunResult) string {
+	params := url.Values{}
+	params.Set(ParamImageName, run.ImageName)
+	params.Set(ParamExitCode, strconv.Itoa(run.ExitCode))
+	params.Set(ParamStartTime, run.StartedAt.Format(time.RFC3339Nano))
+	params.Set(ParamEndTime, run.FinishedAt.Format(time.RFC3339Nano))
+	params.Set(ParamStatus, run.Status())
+	params.Set(ParamAttempt, strconv.Itoa(run.Attempt))
+	params.Set(ParamFinal, strconv.FormatBool(run.Final))
+	params.Set(ParamRunID, run.ID)
+
+	return fmt.Sprintf("%s?%s", c.url, params.Encode())
+}
+
+// gzipReader compresses r on the fly.
+func gzipReader(r io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
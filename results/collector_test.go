@@ -0,0 +1,113 @@
+package results
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/gaia-docker/tugbot/container/mockclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFinishedRun() *container.RunResult {
+	run := container.NewRunResult(container.TriggerTimer, nil)
+	run.ContainerID = "created"
+	run.ContainerName = "tugbot_c_20170101000000"
+	run.ImageName = "tests:latest"
+	run.ExitCode = 1
+	run.StartedAt = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	run.FinishedAt = run.StartedAt.Add(time.Minute)
+
+	return run
+}
+
+func TestCollect(t *testing.T) {
+	var body []byte
+	run := newFinishedRun()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/gzip", r.Header.Get("Content-Type"))
+		assert.Equal(t, "tests:latest", r.URL.Query().Get(ParamImageName))
+		assert.Equal(t, "1", r.URL.Query().Get(ParamExitCode))
+		assert.Equal(t, "2017-01-01T00:00:00Z", r.URL.Query().Get(ParamStartTime))
+		assert.Equal(t, "2017-01-01T00:01:00Z", r.URL.Query().Get(ParamEndTime))
+		assert.Equal(t, container.StatusFailed, r.URL.Query().Get(ParamStatus))
+		assert.Equal(t, "1", r.URL.Query().Get(ParamAttempt))
+		assert.Equal(t, "false", r.URL.Query().Get(ParamFinal))
+		assert.Equal(t, run.ID, r.URL.Query().Get(ParamRunID))
+		zr, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		body, _ = ioutil.ReadAll(zr)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "created").Return(newTestContainer(map[string]string{container.TugbotResultsDir: "/results"}), nil).Once()
+	client.On("CopyFromContainer", "created", "/results").Return(ioutil.NopCloser(bytes.NewBufferString("tar")), nil).Once()
+
+	NewCollector(client, server.URL).Collect(run)
+
+	assert.Equal(t, "tar", string(body))
+	client.AssertExpectations(t)
+}
+
+func TestCollect_TestSet(t *testing.T) {
+	var result Result
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&result))
+	}))
+	defer server.Close()
+
+	archive := newTarArchive(t, map[string]string{"results/api.tap": "ok 1 - login\nnot ok 2 - logout\n"})
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "created").Return(newTestContainer(map[string]string{container.TugbotResultsFormat: "TAP"}), nil).Once()
+	client.On("CopyFromContainer", "created", container.DefaultResultsDir).Return(ioutil.NopCloser(archive), nil).Once()
+
+	run := newFinishedRun()
+	NewCollector(client, server.URL).Collect(run)
+
+	assert.Equal(t, run.ID, result.RunID)
+	assert.Equal(t, "created", result.ContainerID)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "api.tap", result.TestSet.Name)
+	assert.Equal(t, []Test{{Name: "login", Status: StatusPassed}, {Name: "logout", Status: StatusFailed}}, result.TestSet.Tests)
+	client.AssertExpectations(t)
+}
+
+func TestCollect_CopyError(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("Inspect", "created").Return(newTestContainer(nil), nil).Once()
+	client.On("CopyFromContainer", "created", container.DefaultResultsDir).
+		Return(ioutil.NopCloser(&bytes.Buffer{}), errors.New("no such directory")).Once()
+
+	NewCollector(client, "http://localhost:0").Collect(newFinishedRun())
+
+	client.AssertExpectations(t)
+}
+
+func TestCollect_NotStarted(t *testing.T) {
+	client := mockclient.NewMockClient()
+
+	NewCollector(client, "http://localhost:0").Collect(container.NewRunResult(container.TriggerTimer, nil))
+
+	client.AssertExpectations(t)
+}
+
+func TestUpload_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewCollector(nil, server.URL).upload(newFinishedRun(), "application/gzip", &bytes.Buffer{})
+
+	assert.Error(t, err)
+}
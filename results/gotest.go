@@ -0,0 +1,54 @@
+package results
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// ParseGoTest parses 'go test -json' output.
+func ParseGoTest(name string, r io.Reader) (*TestSet, error) {
+	ret := &TestSet{Name: "Go Tests", Tests: []Test{}}
+	output := make(map[string][]string)
+	decoder := json.NewDecoder(r)
+	for {
+		var e goTestEvent
+		if err := decoder.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if e.Test == "" {
+			// package level event
+			if e.Action == "pass" || e.Action == "fail" {
+				ret.Time += e.Elapsed
+			}
+			continue
+		}
+		key := e.Package + "/" + e.Test
+		switch e.Action {
+		case "output":
+			output[key] = append(output[key], e.Output)
+		case "pass", "fail", "skip":
+			test := Test{Name: key, Status: StatusPassed, Time: e.Elapsed}
+			if e.Action == "fail" {
+				test.Status = StatusFailed
+				test.Failure = strings.TrimSpace(strings.Join(output[key], ""))
+			} else if e.Action == "skip" {
+				test.Status = StatusSkipped
+			}
+			ret.Tests = append(ret.Tests, test)
+			delete(output, key)
+		}
+	}
+
+	return ret, nil
+}
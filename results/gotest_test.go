@@ -0,0 +1,36 @@
+package results
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoTest(t *testing.T) {
+	report := `{"Action":"run","Package":"app","Test":"TestA"}
+{"Action":"output","Package":"app","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"pass","Package":"app","Test":"TestA","Elapsed":0.5}
+{"Action":"run","Package":"app","Test":"TestB"}
+{"Action":"output","Package":"app","Test":"TestB","Output":"    b_test.go:10: boom\n"}
+{"Action":"fail","Package":"app","Test":"TestB","Elapsed":0.25}
+{"Action":"skip","Package":"app","Test":"TestC","Elapsed":0}
+{"Action":"fail","Package":"app","Elapsed":1.5}
+`
+
+	set, err := ParseGoTest("go.json", strings.NewReader(report))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, set.Time)
+	assert.Equal(t, []Test{
+		{Name: "app/TestA", Status: StatusPassed, Time: 0.5},
+		{Name: "app/TestB", Status: StatusFailed, Time: 0.25, Failure: "b_test.go:10: boom"},
+		{Name: "app/TestC", Status: StatusSkipped},
+	}, set.Tests)
+}
+
+func TestParseGoTest_Invalid(t *testing.T) {
+	_, err := ParseGoTest("go.json", strings.NewReader("PASS\n"))
+
+	assert.Error(t, err)
+}
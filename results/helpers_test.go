@@ -0,0 +1,18 @@
+package results
+
+import (
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// newTestContainer returns a test container run created by tugbot with labels.
+func newTestContainer(labels map[string]string) *container.Container {
+	return container.NewContainer(
+		&dockerclient.ContainerInfo{
+			Id:     "created",
+			Name:   "tugbot_c_20170101000000",
+			Config: &dockerclient.ContainerConfig{Labels: labels},
+		},
+		nil,
+	)
+}
@@ -0,0 +1,110 @@
+package results
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type junitTestSuites struct {
+	Name   string           `xml:"name,attr"`
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []junitTestCase  `xml:"testcase"`
+	Suites    []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// ParseJUnit parses JUnit XML report, with either <testsuites> or <testsuite> root element.
+func ParseJUnit(name string, r io.Reader) (*TestSet, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		ret := &TestSet{Name: name, Tests: []Test{}}
+		if start.Name.Local == "testsuites" {
+			var suites junitTestSuites
+			if err := decoder.DecodeElement(&suites, &start); err != nil {
+				return nil, err
+			}
+			if suites.Name != "" {
+				ret.Name = suites.Name
+			}
+			for _, suite := range suites.Suites {
+				addJUnitSuite(ret, suite)
+			}
+		} else {
+			var suite junitTestSuite
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return nil, err
+			}
+			if suite.Name != "" {
+				ret.Name = suite.Name
+			}
+			addJUnitSuite(ret, suite)
+		}
+
+		return ret, nil
+	}
+}
+
+func addJUnitSuite(set *TestSet, suite junitTestSuite) {
+	// nested suites time is already included in the parent suite time
+	set.Time += parseSeconds(suite.Time)
+	addJUnitTestCases(set, suite)
+}
+
+func addJUnitTestCases(set *TestSet, suite junitTestSuite) {
+	for _, tc := range suite.TestCases {
+		test := Test{Name: tc.Name, Status: StatusPassed, Time: parseSeconds(tc.Time)}
+		if tc.ClassName != "" {
+			test.Name = tc.ClassName + "." + tc.Name
+		}
+		if failure := tc.Failure; failure != nil || tc.Error != nil {
+			if failure == nil {
+				failure = tc.Error
+			}
+			test.Status = StatusFailed
+			test.Failure = strings.TrimSpace(strings.Join([]string{failure.Message, strings.TrimSpace(failure.Body)}, "\n"))
+		} else if tc.Skipped != nil {
+			test.Status = StatusSkipped
+		}
+		set.Tests = append(set.Tests, test)
+	}
+	for _, nested := range suite.Suites {
+		addJUnitTestCases(set, nested)
+	}
+}
+
+func parseSeconds(val string) float64 {
+	ret, err := strconv.ParseFloat(strings.Replace(val, ",", "", -1), 64)
+	if err != nil {
+		return 0
+	}
+
+	return ret
+}
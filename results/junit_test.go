@@ -0,0 +1,53 @@
+package results
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJUnit_TestSuites(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="API Tests">
+  <testsuite name="users" time="1.5">
+    <testcase classname="users" name="create" time="0.5"/>
+    <testcase classname="users" name="delete" time="1">
+      <failure message="expected 204">got 500</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="orders" time="0.5">
+    <testcase name="list" time="0.5"><skipped/></testcase>
+    <testcase name="get" time="0"><error message="timeout"/></testcase>
+  </testsuite>
+</testsuites>`
+
+	set, err := ParseJUnit("junit.xml", strings.NewReader(report))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "API Tests", set.Name)
+	assert.Equal(t, 2.0, set.Time)
+	assert.Equal(t, []Test{
+		{Name: "users.create", Status: StatusPassed, Time: 0.5},
+		{Name: "users.delete", Status: StatusFailed, Time: 1, Failure: "expected 204\ngot 500"},
+		{Name: "list", Status: StatusSkipped, Time: 0.5},
+		{Name: "get", Status: StatusFailed, Failure: "timeout"},
+	}, set.Tests)
+}
+
+func TestParseJUnit_TestSuite(t *testing.T) {
+	report := `<testsuite name="unit" time="3"><testcase name="a" time="3"/></testsuite>`
+
+	set, err := ParseJUnit("junit.xml", strings.NewReader(report))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "unit", set.Name)
+	assert.Equal(t, 3.0, set.Time)
+	assert.Equal(t, []Test{{Name: "a", Status: StatusPassed, Time: 3}}, set.Tests)
+}
+
+func TestParseJUnit_Invalid(t *testing.T) {
+	_, err := ParseJUnit("junit.xml", strings.NewReader("not xml"))
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package results
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type mochaReport struct {
+	Stats struct {
+		Duration float64 `json:"duration"`
+	} `json:"stats"`
+	Tests   []mochaTest `json:"tests"`
+	Pending []mochaTest `json:"pending"`
+}
+
+type mochaTest struct {
+	FullTitle string  `json:"fullTitle"`
+	Duration  float64 `json:"duration"`
+	Err       struct {
+		Message string `json:"message"`
+		Stack   string `json:"stack"`
+	} `json:"err"`
+}
+
+// ParseMocha parses Mocha JSON reporter output.
+func ParseMocha(name string, r io.Reader) (*TestSet, error) {
+	var report mochaReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	pending := make(map[string]bool)
+	for _, test := range report.Pending {
+		pending[test.FullTitle] = true
+	}
+	ret := &TestSet{Name: "Mocha Tests", Time: report.Stats.Duration / 1000, Tests: []Test{}}
+	for _, mt := range report.Tests {
+		test := Test{Name: mt.FullTitle, Status: StatusPassed, Time: mt.Duration / 1000}
+		if mt.Err.Message != "" || mt.Err.Stack != "" {
+			test.Status = StatusFailed
+			test.Failure = strings.TrimSpace(mt.Err.Message + "\n" + mt.Err.Stack)
+		} else if pending[mt.FullTitle] {
+			test.Status = StatusSkipped
+		}
+		ret.Tests = append(ret.Tests, test)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,38 @@
+package results
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMocha(t *testing.T) {
+	report := `{
+  "stats": {"tests": 3, "passes": 1, "pending": 1, "failures": 1, "duration": 34},
+  "tests": [
+    {"title": "votes", "fullTitle": "api votes", "duration": 12, "err": {}},
+    {"title": "results", "fullTitle": "api results", "duration": 0, "err": {}},
+    {"title": "\"before all\" hook", "fullTitle": "\"before all\" hook", "duration": 0,
+     "err": {"message": "Cannot read property", "stack": "TypeError: Cannot read property"}}
+  ],
+  "pending": [{"title": "results", "fullTitle": "api results", "err": {}}]
+}`
+
+	set, err := ParseMocha("mocha.json", strings.NewReader(report))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Mocha Tests", set.Name)
+	assert.Equal(t, 0.034, set.Time)
+	assert.Equal(t, []Test{
+		{Name: "api votes", Status: StatusPassed, Time: 0.012},
+		{Name: "api results", Status: StatusSkipped},
+		{Name: "\"before all\" hook", Status: StatusFailed, Failure: "Cannot read property\nTypeError: Cannot read property"},
+	}, set.Tests)
+}
+
+func TestParseMocha_Invalid(t *testing.T) {
+	_, err := ParseMocha("mocha.json", strings.NewReader("{"))
+
+	assert.Error(t, err)
+}
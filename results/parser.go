@@ -0,0 +1,118 @@
+package results
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Test statuses
+const (
+	StatusPassed  = "Passed"
+	StatusFailed  = "Failed"
+	StatusSkipped = "Skipped"
+)
+
+// Result is the test run JSON body uploaded to the Result Service.
+type Result struct {
+	RunID       string `json:"RunId"`
+	ImageName   string
+	ContainerID string `json:"ContainerId"`
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	ExitCode    int
+	HostName    string
+	TestSet     *TestSet
+}
+
+// TestSet is a set of tests reported by a test container run.
+type TestSet struct {
+	Name  string
+	Time  float64
+	Tests []Test
+}
+
+// Test is a single test case result.
+type Test struct {
+	Name    string
+	Status  string
+	Time    float64
+	Failure string `json:",omitempty"`
+}
+
+// A Parser parses a single test results file into a TestSet.
+type Parser func(name string, r io.Reader) (*TestSet, error)
+
+type format struct {
+	extensions []string
+	parse      Parser
+}
+
+// formats supported by 'tugbot-results-format' label
+var formats = map[string]format{
+	"junit":  {extensions: []string{".xml"}, parse: ParseJUnit},
+	"tap":    {extensions: []string{".tap"}, parse: ParseTAP},
+	"mocha":  {extensions: []string{".json"}, parse: ParseMocha},
+	"gotest": {extensions: []string{".json", ".jsonl"}, parse: ParseGoTest},
+}
+
+// IsSupportedFormat returns whether or not results format f can be parsed.
+func IsSupportedFormat(f string) bool {
+	_, ok := formats[f]
+
+	return ok
+}
+
+// Parse parses all results files of format f found in tar archive into a single TestSet.
+func Parse(f string, archive io.Reader) (*TestSet, error) {
+	ft, ok := formats[f]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported test results format: %s", f)
+	}
+	ret := &TestSet{Tests: []Test{}}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !hasExtension(header.Name, ft.extensions) {
+			continue
+		}
+		set, err := ft.parse(path.Base(header.Name), tr)
+		if err != nil {
+			log.Warnf("Skipping test results file %s, failed to parse as %s (%v)", header.Name, f, err)
+			continue
+		}
+		merge(ret, set)
+	}
+
+	return ret, nil
+}
+
+func merge(dst *TestSet, src *TestSet) {
+	if dst.Name == "" {
+		dst.Name = src.Name
+	}
+	dst.Time += src.Time
+	dst.Tests = append(dst.Tests, src.Tests...)
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, curr := range extensions {
+		if curr == ext {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package results
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTarArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "results/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+
+	return &buf
+}
+
+func TestParse_MergeFiles(t *testing.T) {
+	archive := newTarArchive(t, map[string]string{
+		"results/a.tap":      "ok 1 - first\n",
+		"results/b.tap":      "not ok 1 - second\n",
+		"results/readme.txt": "not ok 1 - ignored\n",
+	})
+
+	set, err := Parse("tap", archive)
+
+	assert.NoError(t, err)
+	assert.Len(t, set.Tests, 2)
+}
+
+func TestParse_SkipInvalidFile(t *testing.T) {
+	archive := newTarArchive(t, map[string]string{
+		"results/broken.xml": "<testsuite",
+	})
+
+	set, err := Parse("junit", archive)
+
+	assert.NoError(t, err)
+	assert.Empty(t, set.Tests)
+}
+
+func TestParse_UnsupportedFormat(t *testing.T) {
+	_, err := Parse("nunit", &bytes.Buffer{})
+
+	assert.Error(t, err)
+	assert.False(t, IsSupportedFormat("nunit"))
+	assert.True(t, IsSupportedFormat("junit"))
+}
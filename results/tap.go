@@ -0,0 +1,69 @@
+package results
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	tapTestLine  = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*-?\s*([^#]*)(#\s*(.*))?$`)
+	tapDirective = regexp.MustCompile(`(?i)^(skip|todo)\b`)
+)
+
+// ParseTAP parses Test Anything Protocol (TAP) output.
+// Tests marked with SKIP or TODO directive are reported as skipped.
+func ParseTAP(name string, r io.Reader) (*TestSet, error) {
+	ret := &TestSet{Name: name, Tests: []Test{}}
+	var diagnostics []string
+	inYAML := false
+	flush := func() {
+		if len(ret.Tests) > 0 && len(diagnostics) > 0 {
+			last := &ret.Tests[len(ret.Tests)-1]
+			if last.Status == StatusFailed {
+				last.Failure = strings.TrimSpace(strings.Join(append([]string{last.Failure}, diagnostics...), "\n"))
+			}
+		}
+		diagnostics = nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if inYAML {
+			if trimmed == "..." {
+				inYAML = false
+			} else {
+				diagnostics = append(diagnostics, trimmed)
+			}
+			continue
+		}
+		if trimmed == "---" && len(ret.Tests) > 0 {
+			inYAML = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			diagnostics = append(diagnostics, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			continue
+		}
+		match := tapTestLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		flush()
+		test := Test{Name: strings.TrimSpace(match[3]), Status: StatusPassed}
+		if test.Name == "" {
+			test.Name = match[2]
+		}
+		if tapDirective.MatchString(match[5]) {
+			test.Status = StatusSkipped
+		} else if match[1] != "" {
+			test.Status = StatusFailed
+		}
+		ret.Tests = append(ret.Tests, test)
+	}
+	flush()
+
+	return ret, scanner.Err()
+}
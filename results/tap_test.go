@@ -0,0 +1,35 @@
+package results
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTAP(t *testing.T) {
+	report := `TAP version 13
+1..5
+ok 1 - login works
+not ok 2 - logout works
+  ---
+  message: 'expected 200'
+  ...
+ok 3 # SKIP no database
+not ok 4 - search # TODO not implemented
+not ok 5 - upload
+# connection refused
+`
+
+	set, err := ParseTAP("api.tap", strings.NewReader(report))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "api.tap", set.Name)
+	assert.Equal(t, []Test{
+		{Name: "login works", Status: StatusPassed},
+		{Name: "logout works", Status: StatusFailed, Failure: "message: 'expected 200'"},
+		{Name: "3", Status: StatusSkipped},
+		{Name: "search", Status: StatusSkipped},
+		{Name: "upload", Status: StatusFailed, Failure: "connection refused"},
+	}, set.Tests)
+}
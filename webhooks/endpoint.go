@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SignatureHeader is the header carrying HMAC-SHA256 signature of webhook request body,
+// formatted as 'sha256=<hex digest>'.
+const SignatureHeader = "X-Tugbot-Signature"
+
+// Endpoint is a webhook configuration.
+type Endpoint struct {
+	URL string `json:"url"`
+	// Headers are added to each webhook request, for example an API key header
+	Headers map[string]string `json:"headers,omitempty"`
+	// BearerToken is sent in 'Authorization: Bearer <token>' header
+	BearerToken string `json:"bearerToken,omitempty"`
+	// Username and Password are sent using HTTP basic authentication
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// CACert is a PEM file of CA certificates verifying webhook server certificate, instead of system CAs
+	CACert string `json:"caCert,omitempty"`
+	// Cert and Key are PEM files of client certificate and key, for mutual TLS authentication
+	Cert string `json:"cert,omitempty"`
+	Key  string `json:"key,omitempty"`
+	// InsecureSkipVerify disables webhook server certificate verification
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// Secret is HMAC-SHA256 key signing webhook request body, signature is sent in SignatureHeader
+	Secret string `json:"secret,omitempty"`
+	// Preset is the name of built-in payload template: PresetJSON, PresetSlack or PresetTeams
+	Preset string `json:"preset,omitempty"`
+	// Template is Go text/template of payload rendered with Payload, events rendered
+	// to blank payload are not delivered; events are delivered as is without template
+	Template string `json:"template,omitempty"`
+	// ContentType of payload, default: application/json
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// NewEndpoints returns endpoints of plain webhook urls.
+func NewEndpoints(urls []string) []Endpoint {
+	ret := make([]Endpoint, 0, len(urls))
+	for _, u := range urls {
+		ret = append(ret, Endpoint{URL: u})
+	}
+
+	return ret
+}
+
+// LoadEndpoints reads JSON array of webhook configurations from file, configurations
+// are validated by NewPublisher.
+func LoadEndpoints(file string) ([]Endpoint, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Endpoint
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("invalid webhooks configuration %s (%v)", file, err)
+	}
+
+	return ret, nil
+}
+
+// Validate returns an error if webhook configuration is invalid, payload template
+// is parsed by NewPublisher.
+func (e Endpoint) Validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url %s (%v)", e.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid webhook url %s, http or https url is expected", e.URL)
+	}
+	if e.BearerToken != "" && e.Username != "" {
+		return fmt.Errorf("webhook %s: use either bearer token or basic authentication", e.URL)
+	}
+	if (e.Cert == "") != (e.Key == "") {
+		return fmt.Errorf("webhook %s: both client certificate and key are required", e.URL)
+	}
+	if e.Preset != "" && e.Template != "" {
+		return fmt.Errorf("webhook %s: use either payload template preset or template", e.URL)
+	}
+	return nil
+}
+
+// client returns HTTP client sending requests to webhook with timeout.
+func (e Endpoint) client(timeout time.Duration) (*http.Client, error) {
+	if e.CACert == "" && e.Cert == "" && !e.InsecureSkipVerify {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: e.InsecureSkipVerify}
+	if e.CACert != "" {
+		caCert, err := ioutil.ReadFile(e.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("no CA certificates found in " + e.CACert)
+		}
+	}
+	if e.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(e.Cert, e.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Timeout: timeout, Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: config,
+	}}, nil
+}
+
+// authorize sets headers, authentication and body signature of webhook request r.
+func (e Endpoint) authorize(r *http.Request, body []byte) {
+	for k, v := range e.Headers {
+		r.Header.Set(k, v)
+	}
+	if e.BearerToken != "" {
+		r.Header.Set("Authorization", "Bearer "+e.BearerToken)
+	} else if e.Username != "" {
+		r.SetBasicAuth(e.Username, e.Password)
+	}
+	if e.Secret != "" {
+		r.Header.Set(SignatureHeader, Sign(e.Secret, body))
+	}
+}
+
+// Sign returns HMAC-SHA256 signature of body with secret, formatted as 'sha256=<hex digest>'.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
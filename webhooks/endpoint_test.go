@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempFile(t *testing.T, dir string, name string, content string) string {
+	file := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+
+	return file
+}
+
+func TestLoadEndpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tugbot-webhooks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := tempFile(t, dir, "webhooks.json", `[
+		{"url": "http://result-service:8081/events", "headers": {"X-Api-Key": "k3y"}, "secret": "s3cr3t"},
+		{"url": "https://elastic:9200/events/event", "username": "tugbot", "password": "pa55", "caCert": "/etc/ssl/elastic/ca.pem"}
+	]`)
+
+	endpoints, err := LoadEndpoints(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Endpoint{
+		{URL: "http://result-service:8081/events", Headers: map[string]string{"X-Api-Key": "k3y"}, Secret: "s3cr3t"},
+		{URL: "https://elastic:9200/events/event", Username: "tugbot", Password: "pa55", CACert: "/etc/ssl/elastic/ca.pem"},
+	}, endpoints)
+}
+
+func TestLoadEndpoints_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tugbot-webhooks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = LoadEndpoints(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+	_, err = LoadEndpoints(tempFile(t, dir, "object.json", `{"url": "http://result-service:8081/events"}`))
+	assert.Error(t, err)
+}
+
+func TestEndpointValidate(t *testing.T) {
+	assert.NoError(t, Endpoint{URL: "https://result-service/events", BearerToken: "t0ken"}.Validate())
+	assert.Error(t, Endpoint{URL: "result-service/events"}.Validate())
+	assert.Error(t, Endpoint{URL: "ftp://result-service/events"}.Validate())
+	assert.Error(t, Endpoint{URL: "http://result-service/events", BearerToken: "t0ken", Username: "tugbot"}.Validate())
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2
+	assert.Equal(t, "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		Sign("Jefe", []byte("what do ya want for nothing?")))
+}
+
+func TestPublish_Authorized(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, Sign("s3cr3t", body), r.Header.Get(SignatureHeader))
+		received <- r
+	}))
+	defer server.Close()
+	p, err := NewPublisher([]Endpoint{{
+		URL:         server.URL,
+		Headers:     map[string]string{"X-Api-Key": "k3y"},
+		BearerToken: "t0ken",
+		Secret:      "s3cr3t",
+	}}, Config{})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	p.Publish(&dockerclient.Event{Action: "start"})
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "k3y", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "Bearer t0ken", r.Header.Get("Authorization"))
+	case <-time.After(time.Second * 5):
+		t.Fatal("event was not delivered")
+	}
+}
+
+func TestPublish_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tugbot-webhooks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	received := make(chan string, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		received <- user + ":" + password
+	}))
+	defer server.Close()
+	caCert := tempFile(t, dir, "ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	p, err := NewPublisher([]Endpoint{{URL: server.URL, Username: "tugbot", Password: "pa55", CACert: caCert}}, Config{})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	p.Publish(&dockerclient.Event{Action: "start"})
+
+	select {
+	case auth := <-received:
+		assert.Equal(t, "tugbot:pa55", auth)
+	case <-time.After(time.Second * 5):
+		t.Fatal("event was not delivered")
+	}
+}
+
+func TestNewPublisher_Invalid(t *testing.T) {
+	_, err := NewPublisher([]Endpoint{{URL: "https://result-service/events", CACert: "/no/such/ca.pem"}}, Config{})
+	assert.Error(t, err)
+	_, err = NewPublisher(NewEndpoints([]string{"http://result-service/events", "http://result-service/events"}), Config{})
+	assert.EqualError(t, err, "duplicate webhook http://result-service/events")
+	_, err = NewPublisher([]Endpoint{{URL: "https://result-service/events", Cert: "/cert.pem"}}, Config{})
+	assert.EqualError(t, err, "webhook https://result-service/events: both client certificate and key are required")
+	_, err = NewPublisher([]Endpoint{{URL: "http://chat/hook", Template: "{{.Action"}}, Config{})
+	assert.Error(t, err)
+}
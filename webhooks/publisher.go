@@ -0,0 +1,290 @@
+// Package webhooks delivers tugbot events to webhooks. Each webhook has its own
+// delivery queue: failed deliveries are retried with exponential backoff, and
+// undelivered events are kept in a bounded spool, optionally on disk, so they
+// survive tugbot restarts.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+)
+
+// Publisher defaults
+const (
+	DefaultSpoolSize      = 1000
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute * 5
+	DefaultTimeout        = time.Second * 10
+)
+
+// Config is the Publisher configuration, zero values are replaced by defaults.
+type Config struct {
+	// SpoolDir is the directory keeping undelivered events across restarts, empty to keep them in memory only
+	SpoolDir string
+	// SpoolSize is the max number of undelivered events kept per webhook, the oldest events are dropped
+	SpoolSize int
+	// InitialBackoff is the delay before retrying a failed delivery, doubled on each consecutive failure
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between delivery retries
+	MaxBackoff time.Duration
+	// Timeout is the webhook request timeout
+	Timeout time.Duration
+}
+
+// Stats are delivery metrics of a webhook.
+type Stats struct {
+	URL string
+	// Delivered is the number of events delivered
+	Delivered uint64
+	// Failed is the number of failed delivery attempts
+	Failed uint64
+	// Dropped is the number of events dropped: rejected by webhook or pushed out of a full spool
+	Dropped uint64
+	// Pending is the number of events waiting for delivery
+	Pending      int
+	LastError    string    `json:",omitempty"`
+	LastDelivery time.Time `json:",omitempty"`
+}
+
+// Publisher publishes events to webhooks.
+type Publisher struct {
+	endpoints []*endpoint
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+type endpoint struct {
+	Endpoint
+	template *template.Template
+	client   *http.Client
+	config   Config
+	spool    *spool
+	notify   chan struct{}
+	mu       sync.Mutex
+	stats    Stats
+}
+
+// NewPublisher returns a new Publisher delivering events to webhooks, and starts delivering
+// events spooled by previous runs.
+func NewPublisher(webhooks []Endpoint, config Config) (*Publisher, error) {
+	if config.SpoolSize == 0 {
+		config.SpoolSize = DefaultSpoolSize
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = DefaultInitialBackoff
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = DefaultMaxBackoff
+		if config.MaxBackoff < config.InitialBackoff {
+			config.MaxBackoff = config.InitialBackoff
+		}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	p := &Publisher{stop: make(chan struct{})}
+	urls := make(map[string]bool)
+	for _, webhook := range webhooks {
+		if err := webhook.Validate(); err != nil {
+			return nil, err
+		}
+		if urls[webhook.URL] {
+			return nil, fmt.Errorf("duplicate webhook %s", webhook.URL)
+		}
+		urls[webhook.URL] = true
+		tmpl, err := parseTemplate(webhook.Preset, webhook.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid payload template (%v)", webhook.URL, err)
+		}
+		client, err := webhook.client(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure webhook %s TLS (%v)", webhook.URL, err)
+		}
+		dir := ""
+		if config.SpoolDir != "" {
+			// spool directory per webhook
+			hash := sha256.Sum256([]byte(webhook.URL))
+			dir = filepath.Join(config.SpoolDir, hex.EncodeToString(hash[:8]))
+		}
+		s, err := newSpool(dir, config.SpoolSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open webhook spool %s (%v)", dir, err)
+		}
+		if n := s.len(); n > 0 {
+			log.Infof("Found %d undelivered events of webhook %s", n, webhook.URL)
+		}
+		p.endpoints = append(p.endpoints, &endpoint{
+			Endpoint: webhook,
+			template: tmpl,
+			client:   client,
+			config:   config,
+			spool:    s,
+			notify:   make(chan struct{}, 1),
+			stats:    Stats{URL: webhook.URL},
+		})
+	}
+	for _, e := range p.endpoints {
+		p.wg.Add(1)
+		go func(e *endpoint) {
+			e.deliver(p.stop)
+			p.wg.Done()
+		}(e)
+	}
+
+	return p, nil
+}
+
+// Publish queues event e for delivery to all webhooks.
+func (p *Publisher) Publish(e *dockerclient.Event) {
+	for _, endpoint := range p.endpoints {
+		body, err := endpoint.render(e)
+		if err != nil {
+			log.Errorf("Failed to render event %+v for webhook %s (%v)", e, endpoint.URL, err)
+			continue
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			endpoint.push(body)
+		}
+	}
+}
+
+// Stop stops delivering events and waits for in-flight deliveries to finish;
+// undelivered events are left in the spool.
+func (p *Publisher) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Stats returns delivery metrics of each webhook.
+func (p *Publisher) Stats() []Stats {
+	ret := make([]Stats, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		stats := e.stats
+		e.mu.Unlock()
+		stats.Pending = e.spool.len()
+		ret = append(ret, stats)
+	}
+
+	return ret
+}
+
+// render returns webhook payload of event ev: the event JSON, or rendered payload template.
+func (e *endpoint) render(ev *dockerclient.Event) ([]byte, error) {
+	if e.template == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	err := e.template.Execute(&buf, NewPayload(ev))
+
+	return buf.Bytes(), err
+}
+
+func (e *endpoint) push(body []byte) {
+	dropped, err := e.spool.push(body)
+	if err != nil {
+		log.Errorf("Failed to spool event for webhook %s, event dropped (%v)", e.URL, err)
+		dropped = 1
+	}
+	if dropped > 0 {
+		log.Warnf("Webhook %s spool is full, dropped %d oldest events", e.URL, dropped)
+		e.mu.Lock()
+		e.stats.Dropped += uint64(dropped)
+		e.mu.Unlock()
+	}
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver posts spooled events to webhook one by one, until stop is closed.
+func (e *endpoint) deliver(stop chan struct{}) {
+	backoff := e.config.InitialBackoff
+	for {
+		entry, ok := e.spool.front()
+		if !ok {
+			select {
+			case <-e.notify:
+				continue
+			case <-stop:
+				return
+			}
+		}
+		retry, err := e.post(entry.body)
+		e.mu.Lock()
+		if err == nil {
+			e.stats.Delivered++
+			e.stats.LastDelivery = time.Now()
+		} else {
+			e.stats.Failed++
+			e.stats.LastError = err.Error()
+			if !retry {
+				e.stats.Dropped++
+			}
+		}
+		e.mu.Unlock()
+		if err == nil || !retry {
+			if err != nil {
+				log.Errorf("Webhook %s rejected event, event dropped (%v)", e.URL, err)
+			}
+			e.spool.remove(entry.id)
+			backoff = e.config.InitialBackoff
+			continue
+		}
+		log.Warnf("Failed to deliver event to webhook %s, retrying in %s (%v)", e.URL, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-stop:
+			return
+		}
+		backoff *= 2
+		if backoff > e.config.MaxBackoff {
+			backoff = e.config.MaxBackoff
+		}
+	}
+}
+
+// post posts body to webhook, returns error and whether or not delivery should be retried;
+// client errors (4xx), except for request timeout and too many requests, are not retried.
+func (e *endpoint) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	e.authorize(req, body)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected response status: %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return false, err
+	}
+
+	return true, err
+}
@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWebhook returns test webhook server responding with statuses (the last one repeats), and channel of received events
+func newWebhook(t *testing.T, statuses ...int) (*httptest.Server, chan dockerclient.Event) {
+	var calls int32
+	received := make(chan dockerclient.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[i])
+		if statuses[i] < 300 {
+			var e dockerclient.Event
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+			received <- e
+		}
+	}))
+
+	return server, received
+}
+
+func receive(t *testing.T, received chan dockerclient.Event) dockerclient.Event {
+	select {
+	case e := <-received:
+		return e
+	case <-time.After(time.Second * 5):
+		t.Fatal("event was not delivered")
+	}
+
+	return dockerclient.Event{}
+}
+
+func TestPublish(t *testing.T) {
+	first, firstReceived := newWebhook(t, http.StatusOK)
+	defer first.Close()
+	second, secondReceived := newWebhook(t, http.StatusAccepted)
+	defer second.Close()
+	p, err := NewPublisher(NewEndpoints([]string{first.URL, second.URL}), Config{})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	p.Publish(&dockerclient.Event{Type: "container", Action: "start"})
+
+	assert.Equal(t, "start", receive(t, firstReceived).Action)
+	assert.Equal(t, "start", receive(t, secondReceived).Action)
+}
+
+func TestPublish_Retry(t *testing.T) {
+	server, received := newWebhook(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	defer server.Close()
+	p, err := NewPublisher(NewEndpoints([]string{server.URL}), Config{InitialBackoff: time.Millisecond})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	p.Publish(&dockerclient.Event{Type: "container", Action: "die"})
+	p.Publish(&dockerclient.Event{Type: "container", Action: "destroy"})
+
+	assert.Equal(t, "die", receive(t, received).Action)
+	assert.Equal(t, "destroy", receive(t, received).Action)
+	stats := p.Stats()[0]
+	assert.Equal(t, server.URL, stats.URL)
+	assert.Equal(t, uint64(2), stats.Failed)
+	assert.Contains(t, stats.LastError, "429")
+}
+
+func TestPublish_Rejected(t *testing.T) {
+	server, received := newWebhook(t, http.StatusBadRequest, http.StatusOK)
+	defer server.Close()
+	p, err := NewPublisher(NewEndpoints([]string{server.URL}), Config{InitialBackoff: time.Hour})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	p.Publish(&dockerclient.Event{Action: "rejected"})
+	p.Publish(&dockerclient.Event{Action: "delivered"})
+
+	assert.Equal(t, "delivered", receive(t, received).Action)
+	stats := p.Stats()[0]
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestPublish_Spool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tugbot-spool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	status := int32(http.StatusInternalServerError)
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		if atomic.LoadInt32(&status) == http.StatusOK {
+			var e dockerclient.Event
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+			received <- e.Action
+		}
+	}))
+	defer server.Close()
+	p, err := NewPublisher(NewEndpoints([]string{server.URL}), Config{SpoolDir: dir, SpoolSize: 2, InitialBackoff: time.Hour})
+	assert.NoError(t, err)
+
+	p.Publish(&dockerclient.Event{Action: "dropped"})
+	p.Publish(&dockerclient.Event{Action: "first"})
+	p.Publish(&dockerclient.Event{Action: "second"})
+	p.Stop()
+	stats := p.Stats()[0]
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, 2, stats.Pending)
+
+	// restart: events spooled by previous run are delivered first
+	atomic.StoreInt32(&status, http.StatusOK)
+	p, err = NewPublisher(NewEndpoints([]string{server.URL}), Config{SpoolDir: dir})
+	assert.NoError(t, err)
+	defer p.Stop()
+	p.Publish(&dockerclient.Event{Action: "third"})
+
+	for _, action := range []string{"first", "second", "third"} {
+		select {
+		case got := <-received:
+			assert.Equal(t, action, got)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("event %s was not delivered", action)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package webhooks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const spoolFileExt = ".json"
+
+// spool is a bounded FIFO queue of undelivered webhook payloads. When spool has a
+// directory, each payload is kept in a file, so undelivered payloads survive restarts.
+type spool struct {
+	mu      sync.Mutex
+	dir     string
+	size    int
+	entries []spoolEntry
+	seq     int64
+}
+
+type spoolEntry struct {
+	id   string
+	body []byte
+}
+
+// newSpool returns a new spool holding up to size payloads (no limit if not positive),
+// loading payloads left in dir (if not empty) by previous runs.
+func newSpool(dir string, size int) (*spool, error) {
+	s := &spool{dir: dir, size: size, seq: time.Now().UnixNano()}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), spoolFileExt) {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			log.Errorf("Failed to read spooled webhook payload %s (%v)", name, err)
+			continue
+		}
+		s.entries = append(s.entries, spoolEntry{id: strings.TrimSuffix(name, spoolFileExt), body: body})
+	}
+	// shrink spool loaded from a bigger one
+	for s.size > 0 && len(s.entries) > s.size {
+		s.drop()
+	}
+
+	return s, nil
+}
+
+// push appends payload body to the spool, returns the number of payloads dropped to make room for it.
+func (s *spool) push(body []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	entry := spoolEntry{id: fmt.Sprintf("%020d", s.seq), body: body}
+	if s.dir != "" {
+		if err := ioutil.WriteFile(s.path(entry.id), body, 0644); err != nil {
+			return 0, err
+		}
+	}
+	s.entries = append(s.entries, entry)
+	dropped := 0
+	for s.size > 0 && len(s.entries) > s.size {
+		s.drop()
+		dropped++
+	}
+
+	return dropped, nil
+}
+
+// front returns the oldest payload, false if spool is empty.
+func (s *spool) front() (spoolEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) == 0 {
+		return spoolEntry{}, false
+	}
+
+	return s.entries[0], true
+}
+
+// remove removes payload by id, if it has not been dropped already.
+func (s *spool) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, entry := range s.entries {
+		if entry.id == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			s.removeFile(id)
+			return
+		}
+	}
+}
+
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// drop removes the oldest payload, must be called under lock.
+func (s *spool) drop() {
+	s.removeFile(s.entries[0].id)
+	s.entries = s.entries[1:]
+}
+
+func (s *spool) removeFile(id string) {
+	if s.dir != "" {
+		if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to remove spooled webhook payload %s (%v)", id, err)
+		}
+	}
+}
+
+func (s *spool) path(id string) string {
+	return filepath.Join(s.dir, id+spoolFileExt)
+}
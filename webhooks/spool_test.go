@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpool(t *testing.T) {
+	s, err := newSpool("", 2)
+	assert.NoError(t, err)
+
+	_, ok := s.front()
+	assert.False(t, ok)
+	dropped, err := s.push([]byte("1"))
+	assert.NoError(t, err)
+	assert.Zero(t, dropped)
+	s.push([]byte("2"))
+	dropped, _ = s.push([]byte("3"))
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, 2, s.len())
+
+	entry, ok := s.front()
+	assert.True(t, ok)
+	assert.Equal(t, "2", string(entry.body))
+	s.remove(entry.id)
+	entry, _ = s.front()
+	assert.Equal(t, "3", string(entry.body))
+}
+
+func TestSpool_Dir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tugbot-spool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := newSpool(dir, 0)
+	assert.NoError(t, err)
+	s.push([]byte("1"))
+	s.push([]byte("2"))
+	s.push([]byte("3"))
+	entry, _ := s.front()
+	s.remove(entry.id)
+
+	// reopen with smaller size
+	s, err = newSpool(dir, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.len())
+	entry, _ = s.front()
+	assert.Equal(t, "3", string(entry.body))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+	s.push([]byte("4"))
+	entry, _ = s.front()
+	assert.Equal(t, "4", string(entry.body))
+	files, _ = ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+}
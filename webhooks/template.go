@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+)
+
+// Payload template presets
+const (
+	PresetJSON  = "json"
+	PresetSlack = "slack"
+	PresetTeams = "teams"
+)
+
+// presets are built-in payload templates. Slack and Teams presets render only failed
+// test container runs (final attempt), other events are not delivered.
+var presets = map[string]string{
+	PresetJSON: `{"type": {{json .Type}}, "action": {{json .Action}}, "id": {{json .ID}}, "image": {{json .From}}, ` +
+		`"time": {{json .Timestamp}}, "attributes": {{json .Actor.Attributes}}{{if .Run}}, "run": {{json .Run}}{{end}}}`,
+	PresetSlack: `{{if .Failed}}{"text": {{json (printf ":red_circle: %s" .Run.Summary)}}}{{end}}`,
+	PresetTeams: `{{if .Failed}}{"@type": "MessageCard", "@context": "https://schema.org/extensions", "themeColor": "D70000", ` +
+		`"summary": {{json .Run.Summary}}, "title": {{json (printf "Test container %s %s" .Run.Name .Run.Status)}}, ` +
+		`"text": {{json .Run.Summary}}}{{end}}`,
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Payload is the data webhook payload templates are rendered with: the event, its
+// time and the test container run (for tugbot run events).
+type Payload struct {
+	*dockerclient.Event
+	Timestamp time.Time
+	// Run is the test container run described by tugbot run event, nil for Docker events
+	Run *Run
+}
+
+// Run is a test container run described by tugbot run event.
+type Run struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Trigger   string `json:"trigger"`
+	Status    string `json:"status"`
+	Attempt   string `json:"attempt"`
+	Final     bool   `json:"final"`
+	Container string `json:"container,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+	ExitCode  string `json:"exitCode,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NewPayload returns template data of event e.
+func NewPayload(e *dockerclient.Event) Payload {
+	ret := Payload{Event: e, Timestamp: time.Unix(0, e.TimeNano)}
+	if e.TimeNano == 0 {
+		ret.Timestamp = time.Unix(e.Time, 0)
+	}
+	if e.Type == container.EventType {
+		attributes := e.Actor.Attributes
+		ret.Run = &Run{
+			Name:      attributes["name"],
+			ID:        attributes["run-id"],
+			Trigger:   attributes["trigger"],
+			Status:    attributes["status"],
+			Attempt:   attributes["attempt"],
+			Final:     attributes["final"] == "true",
+			Container: attributes["container"],
+			Duration:  attributes["duration"],
+			ExitCode:  attributes["exit-code"],
+			Error:     attributes["error"],
+		}
+	}
+
+	return ret
+}
+
+// Failed returns whether or not the event reports a failed test container run: the final
+// attempt finished with failed, timeout or error status, or test container failed to start.
+func (p Payload) Failed() bool {
+	if p.Run == nil {
+		return false
+	}
+	switch p.Action {
+	case container.EventTestFailedToStart:
+		return true
+	case container.EventTestFinished:
+		return p.Run.Final && p.Run.Status != container.StatusPassed && p.Run.Status != container.StatusPassedAfterRetry
+	}
+
+	return false
+}
+
+// Summary returns a one line description of the run.
+func (r Run) Summary() string {
+	details := []string{}
+	if r.ExitCode != "" && r.Error == "" {
+		details = append(details, "Exit code: "+r.ExitCode)
+	}
+	if r.Duration != "" {
+		details = append(details, "Duration: "+r.Duration)
+	}
+	if r.Attempt != "" && r.Attempt != "1" {
+		details = append(details, "Attempts: "+r.Attempt)
+	}
+	details = append(details, "Trigger: "+r.Trigger, "Run ID: "+r.ID)
+	if r.Error != "" {
+		details = append(details, "Error: "+r.Error)
+	}
+
+	return fmt.Sprintf("Test container %s %s (%s)", r.Name, r.Status, strings.Join(details, ", "))
+}
+
+// parseTemplate returns webhook payload template: preset by name or text, nil if both are empty.
+func parseTemplate(preset string, text string) (*template.Template, error) {
+	if preset != "" {
+		var ok bool
+		if text, ok = presets[preset]; !ok {
+			return nil, fmt.Errorf("unknown payload template preset: %s", preset)
+		}
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	return template.New("payload").Funcs(funcs).Option("missingkey=zero").Parse(text)
+}
@@ -0,0 +1,154 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gaia-docker/tugbot/container"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFinishedRun(status string, exitCode int, final bool) container.RunResult {
+	finished := time.Now()
+	return container.RunResult{
+		ID:            "run1",
+		Trigger:       container.TriggerDocker,
+		Attempt:       2,
+		Final:         final,
+		CreatedFrom:   "api-tests",
+		ContainerName: "tugbot_api-tests_20161012102030",
+		ExitCode:      exitCode,
+		StartedAt:     finished.Add(-time.Second * 42),
+		FinishedAt:    finished,
+		TimedOut:      status == container.StatusTimeout,
+	}
+}
+
+func render(t *testing.T, preset string, text string, e *dockerclient.Event) string {
+	tmpl, err := parseTemplate(preset, text)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&buf, NewPayload(e)))
+
+	return buf.String()
+}
+
+func TestNewPayload(t *testing.T) {
+	e := container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, true))
+	p := NewPayload(e)
+
+	assert.Equal(t, e.TimeNano, p.Timestamp.UnixNano())
+	assert.Equal(t, &Run{
+		Name:      "api-tests",
+		ID:        "run1",
+		Trigger:   container.TriggerDocker,
+		Status:    container.StatusFailed,
+		Attempt:   "2",
+		Final:     true,
+		Container: "tugbot_api-tests_20161012102030",
+		Duration:  "42s",
+		ExitCode:  "1",
+	}, p.Run)
+	assert.True(t, p.Failed())
+	assert.Equal(t, "Test container api-tests failed (Exit code: 1, Duration: 42s, Attempts: 2, Trigger: docker, Run ID: run1)",
+		p.Run.Summary())
+}
+
+func TestNewPayload_DockerEvent(t *testing.T) {
+	p := NewPayload(&dockerclient.Event{Type: "container", Action: "start", Time: 1476267672})
+
+	assert.Nil(t, p.Run)
+	assert.False(t, p.Failed())
+	assert.Equal(t, int64(1476267672), p.Timestamp.Unix())
+}
+
+func TestPayloadFailed(t *testing.T) {
+	assert.False(t, NewPayload(container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, false))).Failed())
+	assert.False(t, NewPayload(container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusPassedAfterRetry, 0, true))).Failed())
+	assert.False(t, NewPayload(container.NewRunEvent(container.EventTestTimeout, newFinishedRun(container.StatusTimeout, 137, true))).Failed())
+	assert.True(t, NewPayload(container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusTimeout, 137, true))).Failed())
+	run := container.NewRunResult(container.TriggerCron, nil)
+	run.Error = "no such image"
+	run.FinishedAt = time.Now()
+	assert.True(t, NewPayload(container.NewRunEvent(container.EventTestFailedToStart, *run)).Failed())
+}
+
+func TestPresetJSON(t *testing.T) {
+	var payload map[string]interface{}
+	out := render(t, PresetJSON, "", &dockerclient.Event{Type: "container", Action: "start", ID: "abc", From: "redis:3",
+		Actor: dockerclient.Actor{Attributes: map[string]string{"name": "redis"}}})
+	assert.NoError(t, json.Unmarshal([]byte(out), &payload))
+	assert.Equal(t, "start", payload["action"])
+	assert.Equal(t, "redis:3", payload["image"])
+	assert.Equal(t, map[string]interface{}{"name": "redis"}, payload["attributes"])
+	assert.Nil(t, payload["run"])
+
+	out = render(t, PresetJSON, "", container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, true)))
+	assert.NoError(t, json.Unmarshal([]byte(out), &payload))
+	assert.Equal(t, "failed", payload["run"].(map[string]interface{})["status"])
+}
+
+func TestPresetSlack(t *testing.T) {
+	var payload map[string]string
+	out := render(t, PresetSlack, "", container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, true)))
+	assert.NoError(t, json.Unmarshal([]byte(out), &payload))
+	assert.Equal(t, ":red_circle: Test container api-tests failed (Exit code: 1, Duration: 42s, Attempts: 2, Trigger: docker, Run ID: run1)",
+		payload["text"])
+
+	assert.Empty(t, render(t, PresetSlack, "", &dockerclient.Event{Type: "container", Action: "die"}))
+	assert.Empty(t, render(t, PresetSlack, "", container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusPassed, 0, true))))
+}
+
+func TestPresetTeams(t *testing.T) {
+	var payload map[string]string
+	out := render(t, PresetTeams, "", container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, true)))
+	assert.NoError(t, json.Unmarshal([]byte(out), &payload))
+	assert.Equal(t, "MessageCard", payload["@type"])
+	assert.Equal(t, "Test container api-tests failed", payload["title"])
+}
+
+func TestTemplate(t *testing.T) {
+	out := render(t, "", `{{.Action}} {{with .Actor.Attributes}}{{.name}}{{end}}`,
+		&dockerclient.Event{Action: "start", Actor: dockerclient.Actor{Attributes: map[string]string{"name": "redis"}}})
+
+	assert.Equal(t, "start redis", out)
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	_, err := parseTemplate("hipchat", "")
+	assert.EqualError(t, err, "unknown payload template preset: hipchat")
+	_, err = parseTemplate("", "{{.Action")
+	assert.Error(t, err)
+	assert.Error(t, Endpoint{URL: "http://chat/hook", Preset: PresetSlack, Template: "{{.Action}}"}.Validate())
+}
+
+func TestPublish_Template(t *testing.T) {
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received <- payload["text"]
+	}))
+	defer server.Close()
+	p, err := NewPublisher([]Endpoint{{URL: server.URL, Preset: PresetSlack}}, Config{})
+	assert.NoError(t, err)
+	defer p.Stop()
+
+	// not rendered: Docker event and passed run
+	p.Publish(&dockerclient.Event{Type: "container", Action: "start"})
+	p.Publish(container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusPassed, 0, true)))
+	p.Publish(container.NewRunEvent(container.EventTestFinished, newFinishedRun(container.StatusFailed, 1, true)))
+
+	select {
+	case text := <-received:
+		assert.Contains(t, text, "api-tests failed")
+	case <-time.After(time.Second * 5):
+		t.Fatal("event was not delivered")
+	}
+	assert.Empty(t, received)
+}